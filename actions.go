@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Enum of actions the player can trigger, independent of the device used
+type Action string
+
+const (
+	ActionMoveUp    Action = "moveUp"
+	ActionMoveDown  Action = "moveDown"
+	ActionMoveLeft  Action = "moveLeft"
+	ActionMoveRight Action = "moveRight"
+	ActionPanCamera Action = "panCamera"
+	ActionSelect    Action = "select"
+	ActionCancel    Action = "cancel"
+	ActionOpenMenu  Action = "openMenu"
+	ActionPause     Action = "pause"
+	ActionSpeedUp   Action = "speedUp"
+	ActionStep      Action = "step"
+	ActionNextWave  Action = "nextWave"
+
+	ActionCursorUp    Action = "cursorUp"
+	ActionCursorDown  Action = "cursorDown"
+	ActionCursorLeft  Action = "cursorLeft"
+	ActionCursorRight Action = "cursorRight"
+	ActionBuild       Action = "build"
+	ActionUpgrade     Action = "upgrade"
+	ActionSell        Action = "sell"
+
+	ActionUndo Action = "undo"
+	ActionRedo Action = "redo"
+)
+
+// Number of tower build slots that get their own action
+const buildSlotCount = 6
+
+// Returns the action that builds the tower in the given slot (0 based)
+func buildSlotAction(slot int) Action {
+	return Action(fmt.Sprintf("buildSlot%d", slot+1))
+}
+
+// Number of hero abilities that get their own action
+const abilitySlotCount = 3
+
+// Returns the action that uses the hero ability in the given slot (0 based)
+func abilityAction(slot int) Action {
+	return Action(fmt.Sprintf("ability%d", slot+1))
+}
+
+// All actions in the order they are shown in the controls window
+func allActions() []Action {
+	actions := []Action{
+		ActionMoveUp,
+		ActionMoveDown,
+		ActionMoveLeft,
+		ActionMoveRight,
+		ActionPanCamera,
+		ActionSelect,
+		ActionCancel,
+		ActionOpenMenu,
+		ActionPause,
+		ActionSpeedUp,
+		ActionStep,
+		ActionNextWave,
+		ActionCursorUp,
+		ActionCursorDown,
+		ActionCursorLeft,
+		ActionCursorRight,
+		ActionBuild,
+		ActionUpgrade,
+		ActionSell,
+		ActionUndo,
+		ActionRedo,
+	}
+	for i := 0; i < buildSlotCount; i++ {
+		actions = append(actions, buildSlotAction(i))
+	}
+	for i := 0; i < abilitySlotCount; i++ {
+		actions = append(actions, abilityAction(i))
+	}
+	return actions
+}
+
+var actionLabels = map[Action]string{
+	ActionMoveUp:    "Move Up",
+	ActionMoveDown:  "Move Down",
+	ActionMoveLeft:  "Move Left",
+	ActionMoveRight: "Move Right",
+	ActionPanCamera: "Pan Camera",
+	ActionSelect:    "Select",
+	ActionCancel:    "Cancel",
+	ActionOpenMenu:  "Open Menu",
+	ActionPause:     "Pause",
+	ActionSpeedUp:   "Speed Up",
+	ActionStep:      "Frame Step",
+	ActionNextWave:  "Next Wave",
+
+	ActionCursorUp:    "Cursor Up",
+	ActionCursorDown:  "Cursor Down",
+	ActionCursorLeft:  "Cursor Left",
+	ActionCursorRight: "Cursor Right",
+	ActionBuild:       "Build",
+	ActionUpgrade:     "Upgrade",
+	ActionSell:        "Sell",
+
+	ActionUndo: "Undo",
+	ActionRedo: "Redo",
+}
+
+func (a Action) Label() string {
+	if l, ok := actionLabels[a]; ok {
+		return l
+	}
+	var slot int
+	if _, err := fmt.Sscanf(string(a), "buildSlot%d", &slot); err == nil {
+		return fmt.Sprintf("Build Slot %d", slot)
+	}
+	if _, err := fmt.Sscanf(string(a), "ability%d", &slot); err == nil {
+		return fmt.Sprintf("Hero Ability %d", slot)
+	}
+	return string(a)
+}
+
+// Enum of devices a binding can come from
+type BindingKind string
+
+const (
+	BindingKey           BindingKind = "key"
+	BindingMouse         BindingKind = "mouse"
+	BindingGamepadButton BindingKind = "pad"
+	BindingGamepadAxis   BindingKind = "axis"
+)
+
+// A single physical input that triggers an action
+type Binding struct {
+	Kind   BindingKind
+	Key    ebiten.Key
+	Mouse  ebiten.MouseButton
+	Button ebiten.StandardGamepadButton
+	Axis   ebiten.StandardGamepadAxis
+	// Direction of the axis that triggers the binding, either -1 or 1
+	Sign int
+}
+
+func keyBinding(k ebiten.Key) Binding {
+	return Binding{Kind: BindingKey, Key: k}
+}
+
+func mouseBinding(b ebiten.MouseButton) Binding {
+	return Binding{Kind: BindingMouse, Mouse: b}
+}
+
+func padBinding(b ebiten.StandardGamepadButton) Binding {
+	return Binding{Kind: BindingGamepadButton, Button: b}
+}
+
+func axisBinding(a ebiten.StandardGamepadAxis, sign int) Binding {
+	return Binding{Kind: BindingGamepadAxis, Axis: a, Sign: sign}
+}
+
+// Reports whether the binding comes from a gamepad rather than keyboard or mouse
+func (b Binding) IsGamepad() bool {
+	return b.Kind == BindingGamepadButton || b.Kind == BindingGamepadAxis
+}
+
+var mouseButtonNames = map[ebiten.MouseButton]string{
+	ebiten.MouseButtonLeft:   "Left",
+	ebiten.MouseButtonRight:  "Right",
+	ebiten.MouseButtonMiddle: "Middle",
+	ebiten.MouseButton3:      "Back",
+	ebiten.MouseButton4:      "Forward",
+}
+
+var gamepadButtonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "LB",
+	ebiten.StandardGamepadButtonFrontTopRight:    "RB",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "LT",
+	ebiten.StandardGamepadButtonFrontBottomRight: "RT",
+	ebiten.StandardGamepadButtonCenterLeft:       "Back",
+	ebiten.StandardGamepadButtonCenterRight:      "Start",
+	ebiten.StandardGamepadButtonLeftStick:        "LS",
+	ebiten.StandardGamepadButtonRightStick:       "RS",
+	ebiten.StandardGamepadButtonLeftTop:          "DPadUp",
+	ebiten.StandardGamepadButtonLeftBottom:       "DPadDown",
+	ebiten.StandardGamepadButtonLeftLeft:         "DPadLeft",
+	ebiten.StandardGamepadButtonLeftRight:        "DPadRight",
+	ebiten.StandardGamepadButtonCenterCenter:     "Home",
+}
+
+var gamepadAxisNames = map[ebiten.StandardGamepadAxis]string{
+	ebiten.StandardGamepadAxisLeftStickHorizontal:  "LeftStickX",
+	ebiten.StandardGamepadAxisLeftStickVertical:    "LeftStickY",
+	ebiten.StandardGamepadAxisRightStickHorizontal: "RightStickX",
+	ebiten.StandardGamepadAxisRightStickVertical:   "RightStickY",
+}
+
+// Formats the binding as "kind:name", the form stored in the settings file
+func (b Binding) String() string {
+	switch b.Kind {
+	case BindingKey:
+		return "key:" + b.Key.String()
+	case BindingMouse:
+		return "mouse:" + mouseButtonNames[b.Mouse]
+	case BindingGamepadButton:
+		return "pad:" + gamepadButtonNames[b.Button]
+	case BindingGamepadAxis:
+		sign := "+"
+		if b.Sign < 0 {
+			sign = "-"
+		}
+		return "axis:" + gamepadAxisNames[b.Axis] + sign
+	}
+	return ""
+}
+
+// Short human readable name of the binding for the UI
+func (b Binding) Label() string {
+	s := b.String()
+	return s[strings.Index(s, ":")+1:]
+}
+
+func parseBinding(s string) (Binding, error) {
+	kind, name, ok := strings.Cut(s, ":")
+	if !ok {
+		return Binding{}, fmt.Errorf("invalid binding %q", s)
+	}
+	switch BindingKind(kind) {
+	case BindingKey:
+		var k ebiten.Key
+		if err := k.UnmarshalText([]byte(name)); err != nil {
+			return Binding{}, err
+		}
+		return keyBinding(k), nil
+	case BindingMouse:
+		for b, n := range mouseButtonNames {
+			if n == name {
+				return mouseBinding(b), nil
+			}
+		}
+	case BindingGamepadButton:
+		for b, n := range gamepadButtonNames {
+			if n == name {
+				return padBinding(b), nil
+			}
+		}
+	case BindingGamepadAxis:
+		sign := 1
+		if strings.HasSuffix(name, "-") {
+			sign = -1
+		}
+		name = strings.TrimRight(name, "+-")
+		for a, n := range gamepadAxisNames {
+			if n == name {
+				return axisBinding(a, sign), nil
+			}
+		}
+	}
+	return Binding{}, fmt.Errorf("unknown binding %q", s)
+}
+
+func defaultBindings() map[Action][]Binding {
+	bindings := map[Action][]Binding{
+		ActionMoveUp: {
+			keyBinding(ebiten.KeyW),
+			keyBinding(ebiten.KeyArrowUp),
+			axisBinding(ebiten.StandardGamepadAxisLeftStickVertical, -1),
+		},
+		ActionMoveDown: {
+			keyBinding(ebiten.KeyS),
+			keyBinding(ebiten.KeyArrowDown),
+			axisBinding(ebiten.StandardGamepadAxisLeftStickVertical, 1),
+		},
+		ActionMoveLeft: {
+			keyBinding(ebiten.KeyA),
+			keyBinding(ebiten.KeyArrowLeft),
+			axisBinding(ebiten.StandardGamepadAxisLeftStickHorizontal, -1),
+		},
+		ActionMoveRight: {
+			keyBinding(ebiten.KeyD),
+			keyBinding(ebiten.KeyArrowRight),
+			axisBinding(ebiten.StandardGamepadAxisLeftStickHorizontal, 1),
+		},
+		ActionPanCamera: {
+			mouseBinding(ebiten.MouseButtonMiddle),
+		},
+		ActionSelect: {
+			mouseBinding(ebiten.MouseButtonLeft),
+			padBinding(ebiten.StandardGamepadButtonRightBottom),
+		},
+		ActionCancel: {
+			mouseBinding(ebiten.MouseButtonRight),
+			keyBinding(ebiten.KeyBackspace),
+			padBinding(ebiten.StandardGamepadButtonRightRight),
+		},
+		ActionOpenMenu: {
+			keyBinding(ebiten.KeyEscape),
+			padBinding(ebiten.StandardGamepadButtonCenterRight),
+		},
+		ActionPause: {
+			keyBinding(ebiten.KeyP),
+			padBinding(ebiten.StandardGamepadButtonRightStick),
+		},
+		ActionSpeedUp: {
+			keyBinding(ebiten.KeyF),
+			padBinding(ebiten.StandardGamepadButtonFrontTopRight),
+		},
+		ActionStep: {
+			keyBinding(ebiten.KeyPeriod),
+		},
+		ActionNextWave: {
+			keyBinding(ebiten.KeyN),
+			padBinding(ebiten.StandardGamepadButtonCenterLeft),
+		},
+		ActionCursorUp: {
+			padBinding(ebiten.StandardGamepadButtonLeftTop),
+			axisBinding(ebiten.StandardGamepadAxisRightStickVertical, -1),
+		},
+		ActionCursorDown: {
+			padBinding(ebiten.StandardGamepadButtonLeftBottom),
+			axisBinding(ebiten.StandardGamepadAxisRightStickVertical, 1),
+		},
+		ActionCursorLeft: {
+			padBinding(ebiten.StandardGamepadButtonLeftLeft),
+			axisBinding(ebiten.StandardGamepadAxisRightStickHorizontal, -1),
+		},
+		ActionCursorRight: {
+			padBinding(ebiten.StandardGamepadButtonLeftRight),
+			axisBinding(ebiten.StandardGamepadAxisRightStickHorizontal, 1),
+		},
+		ActionBuild: {
+			keyBinding(ebiten.KeyB),
+			padBinding(ebiten.StandardGamepadButtonRightLeft),
+		},
+		ActionUpgrade: {
+			keyBinding(ebiten.KeyU),
+			padBinding(ebiten.StandardGamepadButtonRightTop),
+		},
+		ActionSell: {
+			keyBinding(ebiten.KeyX),
+			padBinding(ebiten.StandardGamepadButtonFrontTopLeft),
+		},
+		ActionUndo: {
+			keyBinding(ebiten.KeyZ),
+		},
+		ActionRedo: {
+			keyBinding(ebiten.KeyY),
+		},
+	}
+	for i := 0; i < buildSlotCount; i++ {
+		bindings[buildSlotAction(i)] = []Binding{keyBinding(ebiten.KeyDigit1 + ebiten.Key(i))}
+	}
+	abilityKeys := []ebiten.Key{ebiten.KeyQ, ebiten.KeyE, ebiten.KeyR}
+	abilityButtons := []ebiten.StandardGamepadButton{
+		ebiten.StandardGamepadButtonFrontBottomLeft,
+		ebiten.StandardGamepadButtonFrontBottomRight,
+		ebiten.StandardGamepadButtonLeftStick,
+	}
+	for i := 0; i < abilitySlotCount; i++ {
+		bindings[abilityAction(i)] = []Binding{keyBinding(abilityKeys[i]), padBinding(abilityButtons[i])}
+	}
+	return bindings
+}
@@ -0,0 +1,195 @@
+// Package anim plays spritesheet animations described in data files. Time is
+// whatever clock the caller passes in, the game uses simulation time so
+// animations pause and speed up together with the game.
+package anim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Enum of the animation states entities can be in
+type State string
+
+const (
+	Idle   State = "idle"
+	Walk   State = "walk"
+	Attack State = "attack"
+	Die    State = "die"
+)
+
+// Source rectangle of one frame in the sheet image, and how long it shows in seconds
+type Frame struct {
+	X, Y, W, H int
+	Duration   float64
+}
+
+// Frames of one animation. Directional clips have one frame list per
+// direction, starting at east and going clockwise on screen.
+type Clip struct {
+	Loop       bool
+	Directions [][]Frame
+}
+
+func (c *Clip) Length(dir int) float64 {
+	var l float64
+	for _, f := range c.Directions[dir] {
+		l += f.Duration
+	}
+	return l
+}
+
+type Sheet struct {
+	Image string
+	// Point of the frame placed on the entity's position, in pixels from the top left
+	OriginX, OriginY int
+	Clips            map[State]*Clip
+}
+
+// Sheets by entity type id
+type Library map[string]*Sheet
+
+// On disk representation of a Library
+type libraryFile map[string]struct {
+	Image  string `json:"image"`
+	Origin [2]int `json:"origin"`
+	// Clips by state
+	Animations map[State]struct {
+		Loop bool `json:"loop"`
+		// Seconds per frame, unless Durations says otherwise
+		Duration  float64   `json:"duration"`
+		Durations []float64 `json:"durations,omitempty"`
+		// Frames as x, y, w, h, for clips that look the same in every direction
+		Frames [][4]int `json:"frames,omitempty"`
+		// Frame lists for 4 or 8 directions
+		Directions [][][4]int `json:"directions,omitempty"`
+	} `json:"animations"`
+}
+
+func Load(r io.Reader) (Library, error) {
+	var f libraryFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	lib := Library{}
+	for id, s := range f {
+		sheet := &Sheet{Image: s.Image, OriginX: s.Origin[0], OriginY: s.Origin[1], Clips: map[State]*Clip{}}
+		for state, a := range s.Animations {
+			dirs := a.Directions
+			if a.Frames != nil {
+				dirs = [][][4]int{a.Frames}
+			}
+			if len(dirs) != 1 && len(dirs) != 4 && len(dirs) != 8 {
+				return nil, fmt.Errorf("%s %s: %d directions, want 1, 4 or 8", id, state, len(dirs))
+			}
+			clip := &Clip{Loop: a.Loop}
+			for _, rects := range dirs {
+				if len(rects) == 0 {
+					return nil, fmt.Errorf("%s %s: direction without frames", id, state)
+				}
+				var frames []Frame
+				for i, r := range rects {
+					d := a.Duration
+					if i < len(a.Durations) {
+						d = a.Durations[i]
+					}
+					if d <= 0 {
+						return nil, fmt.Errorf("%s %s: frame %d has no duration", id, state, i)
+					}
+					frames = append(frames, Frame{X: r[0], Y: r[1], W: r[2], H: r[3], Duration: d})
+				}
+				clip.Directions = append(clip.Directions, frames)
+			}
+			sheet.Clips[state] = clip
+		}
+		if sheet.Clips[Idle] == nil {
+			return nil, fmt.Errorf("%s: no idle animation", id)
+		}
+		lib[id] = sheet
+	}
+	return lib, nil
+}
+
+// Animation state of one entity
+type Player struct {
+	sheet *Sheet
+	state State
+	// Heading in radians, 0 is east and angles grow clockwise on screen
+	heading float64
+	// Time the current state started
+	start float64
+}
+
+func NewPlayer(sheet *Sheet, now float64) *Player {
+	return &Player{sheet: sheet, state: Idle, start: now}
+}
+
+func (p *Player) State() State {
+	return p.state
+}
+
+// Switch to a state, restarting the clip only when the state changes.
+// States the sheet has no clip for fall back to idle.
+func (p *Player) Set(state State, now float64) {
+	if p.sheet.Clips[state] == nil {
+		state = Idle
+	}
+	if state != p.state {
+		p.state = state
+		p.start = now
+	}
+}
+
+// Play a state from its first frame even if it is already playing
+func (p *Player) Restart(state State, now float64) {
+	p.state = ""
+	p.Set(state, now)
+}
+
+// Face along a direction, ignored when it has no length
+func (p *Player) Face(dx, dy float64) {
+	if dx != 0 || dy != 0 {
+		p.heading = math.Atan2(dy, dx)
+	}
+}
+
+func (p *Player) clip() *Clip {
+	return p.sheet.Clips[p.state]
+}
+
+// Index of the frame list the heading falls into
+func (p *Player) direction() int {
+	n := len(p.clip().Directions)
+	sector := 2 * math.Pi / float64(n)
+	i := int(math.Round(p.heading / sector))
+	return ((i % n) + n) % n
+}
+
+// Whether a clip that doesn't loop has played to the end
+func (p *Player) Done(now float64) bool {
+	c := p.clip()
+	return !c.Loop && now-p.start >= c.Length(p.direction())
+}
+
+func (p *Player) Frame(now float64) Frame {
+	c := p.clip()
+	dir := p.direction()
+	frames := c.Directions[dir]
+	t := max(0, now-p.start)
+	if c.Loop {
+		t = math.Mod(t, c.Length(dir))
+	}
+	for _, f := range frames {
+		if t < f.Duration {
+			return f
+		}
+		t -= f.Duration
+	}
+	return frames[len(frames)-1]
+}
+
+func (p *Player) Sheet() *Sheet {
+	return p.sheet
+}
@@ -4,5 +4,9 @@ import (
 	"embed"
 )
 
+// Source PNGs are drawn into art/ and packed into one atlas under assets/
+//go:generate go run ./cmd/genart -out art -anim assets/animations.json
+//go:generate go run ./cmd/atlaspack -in art -out assets/atlas -name sprites
+
 //go:embed assets
 var embeddedAssets embed.FS
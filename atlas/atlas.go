@@ -0,0 +1,96 @@
+// Package atlas packs many small images into one texture and describes where
+// each one ended up in a JSON index, so the game can look sprites up by name.
+package atlas
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// Gap left around every sprite so neighbours don't bleed into each other when scaled
+const padding = 1
+
+// Largest atlas side in pixels
+const MaxSize = 4096
+
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+func (r Rect) Image() image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+}
+
+// Where each sprite sits in the atlas image
+type Index struct {
+	// Atlas image file, relative to the index
+	Image   string          `json:"image"`
+	Sprites map[string]Rect `json:"sprites"`
+}
+
+func Load(r io.Reader) (*Index, error) {
+	var idx Index
+	if err := json.NewDecoder(r).Decode(&idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// Pack the images into one atlas. Sprites are placed on shelves, tallest first,
+// and names break ties so the same input always gives the same output.
+func Pack(images map[string]image.Image) (*image.NRGBA, map[string]Rect, error) {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := images[names[i]].Bounds(), images[names[j]].Bounds()
+		if a.Dy() != b.Dy() {
+			return a.Dy() > b.Dy()
+		}
+		return names[i] < names[j]
+	})
+
+	// The narrowest power of two width that makes the atlas no taller than wide
+	for width := 64; width <= MaxSize; width *= 2 {
+		rects, height, ok := shelves(names, images, width)
+		if !ok || height > width {
+			continue
+		}
+		out := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for name, r := range rects {
+			src := images[name]
+			draw.Draw(out, r.Image(), src, src.Bounds().Min, draw.Src)
+		}
+		return out, rects, nil
+	}
+	return nil, nil, fmt.Errorf("sprites don't fit in a %dx%d atlas", MaxSize, MaxSize)
+}
+
+// Lay sprites out in rows of the given width, false if one is wider than that
+func shelves(names []string, images map[string]image.Image, width int) (map[string]Rect, int, bool) {
+	rects := map[string]Rect{}
+	x, y, shelf := padding, padding, 0
+	for _, name := range names {
+		b := images[name].Bounds()
+		if b.Dx()+2*padding > width {
+			return nil, 0, false
+		}
+		if x+b.Dx()+padding > width {
+			x = padding
+			y += shelf + padding
+			shelf = 0
+		}
+		rects[name] = Rect{X: x, Y: y, W: b.Dx(), H: b.Dy()}
+		x += b.Dx() + padding
+		shelf = max(shelf, b.Dy())
+	}
+	return rects, y + shelf + padding, true
+}
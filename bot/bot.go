@@ -0,0 +1,49 @@
+// Package bot plays the simulation automatically for balance testing. Bots
+// only see the world and give the same commands a player would, so whatever
+// they manage a player could too.
+package bot
+
+import "icosahedron.com/tower-defense/sim"
+
+type Bot interface {
+	// Called before every tick, the commands returned are submitted for it
+	Act(w *sim.World) []sim.Command
+}
+
+// Play a new world with b until the game is over or maxTime seconds have
+// passed. Observe is called after every tick, for collecting stats from its
+// events.
+func Play(w *sim.World, b Bot, maxTime float64, observe func(w *sim.World)) {
+	for !w.Over() && w.Time() < maxTime {
+		for _, c := range b.Act(w) {
+			w.Submit(c)
+		}
+		w.Step()
+		if observe != nil {
+			observe(w)
+		}
+	}
+}
+
+// Cost of the command if it is applied now, 0 for free ones and false for ones that would fail
+func price(w *sim.World, c sim.Command) (int, bool) {
+	switch c.Kind {
+	case sim.CmdBuild:
+		t, ok := w.Content().Towers[c.Tower]
+		if !ok || !w.Level().CanBuild(c.Cell) || w.TowerAt(c.Cell) != nil {
+			return 0, false
+		}
+		return t.Cost, true
+	case sim.CmdUpgrade:
+		t := w.Tower(c.TowerID)
+		if t == nil {
+			return 0, false
+		}
+		return t.UpgradeCost()
+	case sim.CmdSell:
+		return 0, w.Tower(c.TowerID) != nil && !w.Level().NoSelling
+	case sim.CmdSetTargeting:
+		return 0, w.Tower(c.TowerID) != nil
+	}
+	return 0, true
+}
@@ -0,0 +1,91 @@
+package bot
+
+import "icosahedron.com/tower-defense/sim"
+
+// Ticks between decisions, so a purchase is applied before the next one is picked
+const decisionTicks = sim.TicksPerSecond / 2
+
+// Spacing of the points along the path used to measure how much of it a tower covers
+const coverageStep = 0.25
+
+// Buys whatever affordable option adds the most damage along the path per
+// gold, as soon as it can. It never sells.
+type Greedy struct {
+	// Call waves early for the bonus whenever the map is clear
+	CallEarly bool
+
+	level *sim.Level
+	// Points along every branch of the road, sampled once per level
+	points []sim.Vec2
+}
+
+// Tiles of path within r of a cell
+func (g *Greedy) coverage(c sim.Cell, r float64) float64 {
+	n := 0
+	for _, p := range g.points {
+		if p.Sub(c.Center()).Len() <= r {
+			n++
+		}
+	}
+	return float64(n) * coverageStep
+}
+
+// Damage per second times the path covered, ignoring splash, slows and armor
+func (g *Greedy) value(t *sim.TowerType, level int, r float64, c sim.Cell) float64 {
+	tower := sim.Tower{Type: t, Level: level}
+	return tower.DPS() * g.coverage(c, r)
+}
+
+func (g *Greedy) Act(w *sim.World) []sim.Command {
+	if w.Tick()%decisionTicks != 0 {
+		return nil
+	}
+	if g.level != w.Level() {
+		g.level = w.Level()
+		g.points = nil
+		for _, b := range g.level.Branches {
+			for d := 0.0; d <= b.Path.Length(); d += coverageStep {
+				g.points = append(g.points, b.Path.PointAt(d))
+			}
+		}
+	}
+
+	var best sim.Command
+	bestValue := 0.0
+	consider := func(c sim.Command, cost int, value float64) {
+		if cost > 0 && cost <= w.Gold() && value/float64(cost) > bestValue {
+			best, bestValue = c, value/float64(cost)
+		}
+	}
+	content := w.Content()
+	for _, id := range content.TowerOrder {
+		t := content.Towers[id]
+		for y := range g.level.Height {
+			for x := range g.level.Width {
+				c := sim.Cell{X: x, Y: y}
+				if !g.level.CanBuild(c) || w.TowerAt(c) != nil {
+					continue
+				}
+				consider(sim.Command{Kind: sim.CmdBuild, Tower: id, Cell: c}, t.Cost, g.value(t, 1, t.Range, c))
+			}
+		}
+	}
+	for _, t := range w.Towers() {
+		cost, ok := t.UpgradeCost()
+		if !ok {
+			continue
+		}
+		next := sim.Tower{Type: t.Type, Level: t.Level + 1}
+		gain := g.value(t.Type, t.Level+1, next.Range(), t.Cell) - g.value(t.Type, t.Level, t.Range(), t.Cell)
+		consider(sim.Command{Kind: sim.CmdUpgrade, TowerID: t.ID}, cost, gain)
+	}
+
+	var commands []sim.Command
+	if bestValue > 0 {
+		commands = append(commands, best)
+	}
+	if g.CallEarly && len(w.Enemies()) == 0 && w.WavesStarted() > 0 && w.EarlyCallBonus() > 0 {
+		commands = append(commands, sim.Command{Kind: sim.CmdCallNextWave})
+	}
+	return commands
+}
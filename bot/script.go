@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+// One step of a Script, towers are referred to by cell since their ids
+// aren't known up front
+type Action struct {
+	// Earliest game time to act in seconds
+	At   float64         `json:"at"`
+	Kind sim.CommandKind `json:"kind"`
+	// Type id of the tower to build
+	Tower string `json:"tower,omitempty"`
+	// Where to build, or the tower to upgrade, sell or retarget
+	Cell      sim.Cell      `json:"cell"`
+	Targeting sim.Targeting `json:"targeting,omitempty"`
+}
+
+// On disk build script
+type scriptFile struct {
+	Actions []Action `json:"actions"`
+}
+
+func LoadActions(r io.Reader) ([]Action, error) {
+	var f scriptFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	return f.Actions, nil
+}
+
+func SaveActions(w io.Writer, actions []Action) error {
+	data, err := json.MarshalIndent(scriptFile{Actions: actions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Follows a list of actions like a player saving up: each action waits for
+// its time and for enough gold, and the ones after it wait their turn
+type Script struct {
+	actions []Action
+	next    int
+	// Log actions that are skipped because they can never work
+	verbose bool
+}
+
+func NewScript(actions []Action, verbose bool) *Script {
+	return &Script{actions: actions, verbose: verbose}
+}
+
+// Actions that can never work, like building on the road, are skipped
+func (s *Script) Act(w *sim.World) []sim.Command {
+	var commands []sim.Command
+	for s.next < len(s.actions) {
+		a := s.actions[s.next]
+		if w.Time() < a.At {
+			break
+		}
+		c := sim.Command{Kind: a.Kind, Tower: a.Tower, Cell: a.Cell, Targeting: a.Targeting}
+		if a.Kind != sim.CmdBuild && a.Kind != sim.CmdCallNextWave {
+			if t := w.TowerAt(a.Cell); t != nil {
+				c.TowerID = t.ID
+			}
+		}
+		cost, ok := price(w, c)
+		if !ok {
+			if s.verbose {
+				log.Printf("Skipping action %d: can't %s at %d,%d", s.next, a.Kind, a.Cell.X, a.Cell.Y)
+			}
+			s.next++
+			continue
+		}
+		if w.Gold() < cost {
+			break
+		}
+		commands = append(commands, c)
+		s.next++
+		// Gold is only spent when the command is applied, so wait a tick before the next purchase
+		if cost > 0 {
+			break
+		}
+	}
+	return commands
+}
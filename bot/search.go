@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"slices"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Towers further than this from the path are never worth trying
+const searchCellDistance = 2
+
+type SearchOptions struct {
+	// Build orders tried, half of them random and half tweaks of the best so far
+	Iterations int
+	// Level seeds every build order is played on
+	Seeds []uint64
+	// Applied for every seed, since what some of them do depends on it
+	Modifiers []string
+	// Play on with generated waves after the level's own
+	Endless bool
+	// Seeds the random choices of the search itself
+	Seed    uint64
+	MaxTime float64
+}
+
+// Points for how well a game went: winning beats getting further, which beats keeping lives and gold
+func rate(w *sim.World) float64 {
+	score := float64(w.WavesStarted()*10000 + w.Score())
+	if w.Won() {
+		score += 1e6
+	}
+	return score
+}
+
+// Find a good build order for a level by trying random ones and keeping the
+// one with the best average result over the seeds. Play it with NewScript.
+// The level and content are taken without modifiers, nil is returned when
+// one of them is unknown.
+func RandomSearch(level *sim.Level, content *sim.Content, opts SearchOptions) []Action {
+	// Whether selling is allowed doesn't depend on the seed
+	modified, _, err := sim.ApplyModifiers(level, content, opts.Modifiers)
+	if err != nil {
+		return nil
+	}
+	var cells []sim.Cell
+	for y := range level.Height {
+		for x := range level.Width {
+			c := sim.Cell{X: x, Y: y}
+			if level.CanBuild(c) && level.DistanceToPath(c.Center()) <= searchCellDistance {
+				cells = append(cells, c)
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+	s := &searcher{level: level, content: content, opts: opts, noSelling: modified.NoSelling, cells: cells, rng: sim.NewRNG(opts.Seed)}
+
+	var best []Action
+	bestScore := 0.0
+	for i := range opts.Iterations {
+		var actions []Action
+		if best == nil || i%2 == 0 {
+			actions = s.randomActions()
+		} else {
+			actions = s.mutate(best)
+		}
+		if score := s.evaluate(actions); best == nil || score > bestScore {
+			best, bestScore = actions, score
+		}
+	}
+	return best
+}
+
+type searcher struct {
+	level   *sim.Level
+	content *sim.Content
+	opts    SearchOptions
+	// Set by one of the modifiers
+	noSelling bool
+	// Where towers may go
+	cells []sim.Cell
+	rng   sim.RNG
+}
+
+func (s *searcher) evaluate(actions []Action) float64 {
+	total := 0.0
+	for _, seed := range s.opts.Seeds {
+		seeded := *s.level
+		seeded.Seed = seed
+		// Checked by RandomSearch already
+		l, c, _ := sim.ApplyModifiers(&seeded, s.content, s.opts.Modifiers)
+		var w *sim.World
+		if s.opts.Endless {
+			w = sim.NewEndlessWorld(l, c)
+		} else {
+			w = sim.NewWorld(l, c)
+		}
+		Play(w, NewScript(actions, false), s.opts.MaxTime, nil)
+		total += rate(w)
+	}
+	return total / float64(max(1, len(s.opts.Seeds)))
+}
+
+// Build on a random cell, or upgrade or sell one built on earlier in
+// actions, so orders that sell and build elsewhere can be found too
+func (s *searcher) randomAction(actions []Action) Action {
+	var built []sim.Cell
+	for _, a := range actions {
+		if a.Kind == sim.CmdBuild {
+			built = append(built, a.Cell)
+		}
+	}
+	if len(built) > 0 {
+		switch r := s.rng.Float64(); {
+		case r < 0.3:
+			return Action{Kind: sim.CmdUpgrade, Cell: built[s.rng.Intn(len(built))]}
+		case r < 0.4 && !s.noSelling:
+			return Action{Kind: sim.CmdSell, Cell: built[s.rng.Intn(len(built))]}
+		}
+	}
+	order := s.content.TowerOrder
+	return Action{Kind: sim.CmdBuild, Tower: order[s.rng.Intn(len(order))], Cell: s.cells[s.rng.Intn(len(s.cells))]}
+}
+
+func (s *searcher) randomActions() []Action {
+	n := 6 + s.rng.Intn(15)
+	var actions []Action
+	for range n {
+		actions = append(actions, s.randomAction(actions))
+	}
+	return actions
+}
+
+// Replace, insert, drop or swap a few actions
+func (s *searcher) mutate(actions []Action) []Action {
+	actions = slices.Clone(actions)
+	for range 1 + s.rng.Intn(3) {
+		i := s.rng.Intn(len(actions))
+		switch s.rng.Intn(4) {
+		case 0:
+			actions[i] = s.randomAction(actions[:i])
+		case 1:
+			actions = slices.Insert(actions, i, s.randomAction(actions[:i]))
+		case 2:
+			if len(actions) > 1 {
+				actions = slices.Delete(actions, i, i+1)
+			}
+		case 3:
+			j := s.rng.Intn(len(actions))
+			actions[i], actions[j] = actions[j], actions[i]
+		}
+	}
+	return actions
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// Scale the game world is drawn at, in screen pixels per game unit
+	defaultZoom = 4
+	// Smallest comfortable touch target in screen pixels, tiles never get smaller than this
+	minTouchTarget = 44
+	minZoom        = float64(minTouchTarget) / tileSize
+	maxZoom        = 10
+)
+
+type Camera struct {
+	// World position shown at the top left corner of the screen
+	position mgl32.Vec2
+	zoom     float64
+}
+
+func NewCamera() Camera {
+	return Camera{
+		position: mgl32.Vec2{0, 0},
+		zoom:     defaultZoom,
+	}
+}
+
+// Transform from world coordinates to screen coordinates
+func (c *Camera) GeoM() ebiten.GeoM {
+	var m ebiten.GeoM
+	m.Translate(float64(-c.position[0]), float64(-c.position[1]))
+	m.Scale(c.zoom, c.zoom)
+	return m
+}
+
+func (c *Camera) ScreenToWorld(x, y int) mgl32.Vec2 {
+	return mgl32.Vec2{
+		float32(float64(x)/c.zoom) + c.position[0],
+		float32(float64(y)/c.zoom) + c.position[1],
+	}
+}
+
+func (c *Camera) WorldToScreen(p mgl32.Vec2) (float64, float64) {
+	return float64(p[0]-c.position[0]) * c.zoom, float64(p[1]-c.position[1]) * c.zoom
+}
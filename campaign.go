@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// A new world on campaign level i with the modifiers applied to it, or
+// without any when they can't be
+func (g *Game) newWorld(i int, modifiers []string, endless bool) *sim.World {
+	level, content, err := sim.ApplyModifiers(g.campaign[i], sim.DefaultContent(), modifiers)
+	if err != nil {
+		log.Println("Failed to apply modifiers:", err)
+		level, content = g.campaign[i], sim.DefaultContent()
+	}
+	if endless {
+		return sim.NewEndlessWorld(level, content)
+	}
+	return sim.NewWorld(level, content)
+}
+
+// Replace the running game with a fresh one on a campaign level, with the
+// difficulty and modifiers from the level setup
+func (g *Game) startLevel(i int) {
+	g.startWorld(i, g.newWorld(i, g.settings.levelModifiers(), false), nil)
+}
+
+// Play the map of a campaign level with endless waves
+func (g *Game) startEndless(i int) {
+	g.startWorld(i, g.newWorld(i, g.settings.levelModifiers(), true), nil)
+}
+
+// Start the game that is being played over
+func (g *Game) restart() {
+	if g.daily != nil {
+		if err := g.startChallenge(*g.daily); err != nil {
+			log.Println("Failed to restart the daily challenge:", err)
+		}
+		return
+	}
+	// With the modifiers it was played with, even if the setup changed since
+	g.startWorld(g.level, g.newWorld(g.level, g.world.Level().Modifiers, g.world.Endless()), nil)
+}
+
+// Replace the running game with w, played on campaign level i, or watched
+// when p is the playback it comes from
+func (g *Game) startWorld(i int, w *sim.World, p *sim.Playback) {
+	for len(g.windowClosers) > 0 {
+		g.closeWindow()
+	}
+	// Endless games count however far they got, even when left early, but
+	// watching one doesn't
+	if g.world != nil && g.world.Endless() && !g.resultsShown && g.playback == nil {
+		g.recordResults()
+	}
+	g.level = i
+	g.world = w
+	g.playback = p
+	g.daily = nil
+	g.resultsShown = false
+	g.combatText = NewCombatText()
+	g.sprites = NewSprites()
+	g.particles = NewParticles()
+	g.buildType = ""
+	g.selectedTower = 0
+	g.hoveredTower = 0
+	g.tooltip.Hide()
+	g.hoverTip.Hide()
+	g.cursor.visible = false
+	g.player = NewPlayer()
+	g.camera = NewCamera()
+	g.speed = NewSpeedControl()
+	// The build bar, wave preview and minimap all depend on the level
+	g.ui = g.getEbitenUI()
+}
+
+// Save the result and show it once the game is over
+func (g *Game) updateResults() {
+	if g.resultsShown || g.window != None || !g.world.Over() || g.playback != nil {
+		return
+	}
+	g.resultsShown = true
+	g.recordResults()
+	openResults(g)
+}
+
+func (g *Game) recordResults() {
+	if g.daily != nil {
+		g.profile.RecordDaily(*g.daily, g.world)
+	} else {
+		g.profile.Record(g.world)
+	}
+	if err := g.profile.save(); err != nil {
+		log.Println("Failed to save profile:", err)
+	}
+}
+
+// Title bar with a close button, shared by the campaign windows
+func newWindowTitleBar(g *Game, res *uiResources, title string, face font.Face) *widget.Container {
+	titleFace, _ := loadFont(24)
+	titleBar := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(widget.GridLayoutOpts.Columns(2), widget.GridLayoutOpts.Stretch([]bool{true, false}, []bool{true}), widget.GridLayoutOpts.Padding(widget.Insets{
+			Left:   30,
+			Right:  5,
+			Top:    6,
+			Bottom: 5,
+		}))))
+
+	titleBar.AddChild(widget.NewText(
+		widget.TextOpts.Text(title, titleFace, res.textInput.color.Idle),
+		widget.TextOpts.Position(widget.TextPositionStart, widget.TextPositionCenter),
+	))
+
+	titleBar.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("X", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			g.closeWindow()
+		}),
+		widget.ButtonOpts.TabOrder(99),
+	))
+	return titleBar
+}
+
+// Row of three stars with the earned ones filled in
+func newStars(stars int) *widget.Container {
+	c := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(2),
+		)),
+		widget.ContainerOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.GridLayoutData{
+			VerticalPosition: widget.GridLayoutPositionCenter,
+		})),
+	)
+	for i := range 3 {
+		c.AddChild(widget.NewGraphic(widget.GraphicOpts.Image(starIcon(i < stars))))
+	}
+	return c
+}
+
+// Every campaign level with its rating, locked ones can't be played yet
+func openLevelSelect(g *Game) {
+	res, _ := newUIResources()
+	face, _ := loadFont(20)
+	smallFace, _ := loadFont(16)
+	content := sim.DefaultContent()
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(
+			widget.NewGridLayout(
+				widget.GridLayoutOpts.Columns(5),
+				widget.GridLayoutOpts.Stretch([]bool{true, false, false, false, false}, nil),
+				widget.GridLayoutOpts.Padding(res.panel.padding),
+				widget.GridLayoutOpts.Spacing(15, 8),
+			),
+		),
+	)
+
+	for i, l := range g.campaign {
+		progress := g.profile.Progress(l.ID)
+		unlocked := g.profile.Unlocked(g.campaign, i)
+		c.AddChild(widget.NewText(
+			widget.TextOpts.Text(fmt.Sprintf("%d. %s", i+1, l.Name), face, res.label.text.Idle),
+			widget.TextOpts.Position(widget.TextPositionStart, widget.TextPositionCenter),
+		))
+		c.AddChild(newStars(progress.Stars))
+		best := "-"
+		if progress.Completed {
+			best = fmt.Sprintf("Best %d", progress.BestScore)
+			if m, ok := content.Modifiers[progress.Hardest]; ok {
+				best += " on " + m.Name
+			}
+		}
+		c.AddChild(widget.NewText(
+			widget.TextOpts.Text(best, smallFace, res.text.idleColor),
+			widget.TextOpts.Position(widget.TextPositionEnd, widget.TextPositionCenter),
+		))
+		label := "Play"
+		if !unlocked {
+			label = "Locked"
+		}
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(res.button.padding),
+			widget.ButtonOpts.Text(label, face, res.button.text),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				openLevelSetup(g, i, false)
+			}),
+		)
+		b.GetWidget().Disabled = !unlocked
+		c.AddChild(b)
+
+		// Endless mode opens up once the level's own waves were beaten
+		label = "Endless"
+		if progress.BestEndlessWave > 0 {
+			label = fmt.Sprintf("Endless (wave %d)", progress.BestEndlessWave)
+		}
+		endless := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(res.button.padding),
+			widget.ButtonOpts.Text(label, smallFace, res.button.text),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				openLevelSetup(g, i, true)
+			}),
+		)
+		endless.GetWidget().Disabled = !progress.Completed
+		c.AddChild(endless)
+	}
+
+	window := widget.NewWindow(
+		widget.WindowOpts.Modal(),
+		widget.WindowOpts.Contents(c),
+		widget.WindowOpts.TitleBar(newWindowTitleBar(g, res, "Level Select", face), 30),
+		widget.WindowOpts.Draggable(),
+	)
+	windowSize := input.GetWindowSize()
+	r := image.Rect(0, 0, 760, 90+50*len(g.campaign))
+	r = r.Add(image.Point{(windowSize.X - r.Dx()) / 2, (windowSize.Y - r.Dy()) / 2})
+	window.SetLocation(r)
+
+	previous := g.window
+	g.window = LevelSelect
+	rw := g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		g.window = previous
+		rw()
+	})
+}
+
+// Outcome of the game that just ended and where to go from here
+func openResults(g *Game) {
+	res, _ := newUIResources()
+	face, _ := loadFont(20)
+	w := g.world
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(res.panel.padding),
+			widget.RowLayoutOpts.Spacing(12),
+		)),
+	)
+
+	title := "Defeat"
+	progress := g.profile.Progress(w.Level().ID)
+	lines := []string{
+		w.Level().Name,
+		fmt.Sprintf("Lives %d/%d   Gold %d", w.Lives(), w.Level().StartingLives, w.Gold()),
+		fmt.Sprintf("Score %d   Best %d", w.Score(), progress.BestScore),
+	}
+	switch {
+	case g.daily != nil:
+		title = "Daily Challenge"
+		r, _ := g.profile.Daily(g.daily.Date)
+		lines = append([]string{w.Level().Name + " " + g.daily.Date}, scoreLines(w.ScoreBreakdown())...)
+		lines = append(lines, fmt.Sprintf("Best today %d", r.Best.Total))
+		if w.Won() {
+			c.AddChild(newStars(w.Stars()))
+		}
+	case w.Endless():
+		title = "Endless"
+		lines = []string{
+			w.Level().Name + " (endless)",
+			fmt.Sprintf("Reached wave %d   Best %d", w.WavesStarted(), progress.BestEndlessWave),
+		}
+	case w.Won():
+		title = "Victory"
+		c.AddChild(newStars(w.Stars()))
+	}
+	lines = append(lines, modifierNames(w.Content(), w.Level().Modifiers))
+	for _, l := range lines {
+		c.AddChild(widget.NewText(widget.TextOpts.Text(l, face, res.label.text.Idle)))
+	}
+
+	bc := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(15),
+		)),
+	)
+	c.AddChild(bc)
+	newButton := func(label string, handler func()) *widget.Button {
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(res.button.padding),
+			widget.ButtonOpts.Text(label, face, res.button.text),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				handler()
+			}),
+		)
+		bc.AddChild(b)
+		return b
+	}
+	newButton("Retry", g.restart)
+	next := g.level + 1
+	if w.Won() && g.daily == nil && next < len(g.campaign) {
+		newButton("Next Level", func() { openLevelSetup(g, next, false) })
+	}
+	if w.Won() && g.daily == nil {
+		newButton("Endless", func() { openLevelSetup(g, g.level, true) })
+	}
+	newButton("Level Select", func() { openLevelSelect(g) })
+	status := widget.NewText(widget.TextOpts.Text("", face, res.label.text.Idle))
+	newButton("Save Replay", func() {
+		if _, err := g.saveReplay(); err != nil {
+			log.Println("Failed to save replay:", err)
+			status.Label = "Failed to save the replay"
+			return
+		}
+		status.Label = "Replay saved"
+	})
+	c.AddChild(status)
+
+	window := widget.NewWindow(
+		widget.WindowOpts.Modal(),
+		widget.WindowOpts.Contents(c),
+		widget.WindowOpts.TitleBar(newWindowTitleBar(g, res, title, face), 30),
+		widget.WindowOpts.Draggable(),
+	)
+	windowSize := input.GetWindowSize()
+	r := image.Rect(0, 0, 760, 300)
+	r = r.Add(image.Point{(windowSize.X - r.Dx()) / 2, (windowSize.Y - r.Dy()) / 2})
+	window.SetLocation(r)
+
+	g.window = Results
+	g.speed.PauseForMenu()
+	rw := g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		g.window = None
+		g.speed.ResumeFromMenu()
+		rw()
+	})
+	if g.input.FromGamepad(ActionSelect) {
+		g.focusFirstWidget()
+	}
+}
@@ -0,0 +1,86 @@
+// Command atlaspack combines the PNGs under a directory into one texture atlas
+// and writes a JSON index of where each one went. Sprites are named by their
+// path relative to the input directory without the extension, like
+// "tiles/grass".
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"image"
+	"image/png"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"icosahedron.com/tower-defense/atlas"
+)
+
+func main() {
+	in := flag.String("in", "art", "directory of PNGs to pack")
+	out := flag.String("out", "assets/atlas", "directory to write the atlas to")
+	name := flag.String("name", "sprites", "file name of the atlas image and index, without extension")
+	flag.Parse()
+
+	images := map[string]image.Image{}
+	err := filepath.WalkDir(*in, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".png" {
+			return err
+		}
+		rel, err := filepath.Rel(*in, path)
+		if err != nil {
+			return err
+		}
+		img, err := readPNG(path)
+		if err != nil {
+			return err
+		}
+		images[strings.TrimSuffix(filepath.ToSlash(rel), ".png")] = img
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	img, rects, err := atlas.Pack(images)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := writePNG(filepath.Join(*out, *name+".png"), img); err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(atlas.Index{Image: *name + ".png", Sprites: rects}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, *name+".json"), append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Packed %d sprites into a %dx%d atlas", len(rects), img.Bounds().Dx(), img.Bounds().Dy())
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
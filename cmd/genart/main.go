@@ -0,0 +1,356 @@
+// Command genart draws the project's tiles and sprite sheets as individual
+// PNGs for atlaspack, and writes the animation definitions that describe the
+// sheets. Run it from the repository root with `go generate` after changing
+// how anything looks.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+type enemySpec struct {
+	id     string
+	color  string
+	radius float64
+	// Frame width and height in pixels
+	size       int
+	directions int
+	flying     bool
+	// Seconds per walk frame
+	walk float64
+	// Extra detail drawn on top of the body
+	decorate func(img *image.NRGBA, cx, cy, r float64, c color.NRGBA)
+}
+
+type towerSpec struct {
+	id    string
+	color string
+	top   func(img *image.NRGBA, flash float64, c color.NRGBA)
+}
+
+var enemies = []enemySpec{
+	{id: "grunt", color: "c0503a", radius: 5, size: 16, directions: 4, walk: 0.12},
+	{id: "runner", color: "e7c34b", radius: 4, size: 16, directions: 4, walk: 0.08},
+	{id: "brute", color: "7a3b8f", radius: 7, size: 16, directions: 4, walk: 0.18},
+	{id: "bat", color: "6fa8dc", radius: 3.5, size: 16, directions: 8, flying: true, walk: 0.07},
+	{id: "knight", color: "a7b1b7", radius: 6, size: 16, directions: 4, walk: 0.14, decorate: drawHelmet},
+	{id: "ogre", color: "4e7d32", radius: 9, size: 24, directions: 4, walk: 0.2, decorate: drawCrown},
+	// Not an enemy, but the hero walks and dies the same way
+	{id: "hero", color: "3f7fd0", radius: 5.5, size: 16, directions: 4, walk: 0.12, decorate: drawPlume},
+}
+
+var towers = []towerSpec{
+	{id: "arrow", color: "8a6a3f", top: drawArrowTop},
+	{id: "cannon", color: "4b4f54", top: drawCannonTop},
+	{id: "mage", color: "6a4fb0", top: drawCrystalTop},
+	{id: "frost", color: "6fc3df", top: drawCrystalTop},
+}
+
+const towerSize = 16
+
+// Mirrors anim's on disk format
+type animation struct {
+	Loop       bool       `json:"loop"`
+	Duration   float64    `json:"duration"`
+	Frames     [][4]int   `json:"frames,omitempty"`
+	Directions [][][4]int `json:"directions,omitempty"`
+}
+
+type sheet struct {
+	Image      string                `json:"image"`
+	Origin     [2]int                `json:"origin"`
+	Animations map[string]*animation `json:"animations"`
+}
+
+func main() {
+	out := flag.String("out", "art", "directory to write the PNGs to")
+	animations := flag.String("anim", "assets/animations.json", "file to write the animation definitions to")
+	flag.Parse()
+
+	dir := filepath.Join(*out, "sprites")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	// Sheets refer to their image by its name in the atlas
+	sheets := map[string]*sheet{}
+	for _, e := range enemies {
+		img, s := drawEnemySheet(e)
+		s.Image = "sprites/" + e.id
+		writePNG(filepath.Join(dir, e.id+".png"), img)
+		sheets[e.id] = s
+	}
+	for _, t := range towers {
+		img, s := drawTowerSheet(t)
+		s.Image = "sprites/tower-" + t.id
+		writePNG(filepath.Join(dir, "tower-"+t.id+".png"), img)
+		sheets["tower-"+t.id] = s
+	}
+	writeTiles(filepath.Join(*out, "tiles"))
+
+	data, err := json.MarshalIndent(sheets, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Keep each frame rect on one line
+	rect := regexp.MustCompile(`\[\s+(\d+),\s+(\d+),\s+(\d+),\s+(\d+)\s+\]`)
+	data = rect.ReplaceAll(data, []byte("[$1, $2, $3, $4]"))
+	point := regexp.MustCompile(`\[\s+(\d+),\s+(\d+)\s+\]`)
+	data = point.ReplaceAll(data, []byte("[$1, $2]"))
+	if err := os.WriteFile(*animations, append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writePNG(path string, img image.Image) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Rows: idle, one walk row per direction, die
+func drawEnemySheet(e enemySpec) (*image.NRGBA, *sheet) {
+	const columns = 4
+	s := e.size
+	img := image.NewNRGBA(image.Rect(0, 0, columns*s, (e.directions+2)*s))
+	c := hexColor(e.color)
+	sh := &sheet{Origin: [2]int{s / 2, s / 2}, Animations: map[string]*animation{}}
+	rect := func(col, row int) [4]int {
+		return [4]int{col * s, row * s, s, s}
+	}
+	frame := func(col, row int) *image.NRGBA {
+		return img.SubImage(image.Rect(col*s, row*s, (col+1)*s, (row+1)*s)).(*image.NRGBA)
+	}
+
+	// Idle breathes while facing the viewer
+	idle := &animation{Loop: true, Duration: 0.4}
+	for i := range 2 {
+		drawEnemy(frame(i, 0), e, c, math.Pi/2, 0, float64(-i), 1, 255)
+		idle.Frames = append(idle.Frames, rect(i, 0))
+	}
+	sh.Animations["idle"] = idle
+
+	walk := &animation{Loop: true, Duration: e.walk}
+	for d := range e.directions {
+		heading := 2 * math.Pi * float64(d) / float64(e.directions)
+		var frames [][4]int
+		for i := range columns {
+			step := []float64{1, 0, -1, 0}[i]
+			bob := []float64{0, -1, 0, -1}[i]
+			drawEnemy(frame(i, d+1), e, c, heading, step, bob, 1, 255)
+			frames = append(frames, rect(i, d+1))
+		}
+		walk.Directions = append(walk.Directions, frames)
+	}
+	sh.Animations["walk"] = walk
+
+	die := &animation{Duration: 0.1}
+	row := e.directions + 1
+	for i := range columns {
+		scale := 1 - 0.2*float64(i)
+		dark := shade(c, 1-0.2*float64(i))
+		drawEnemy(frame(i, row), e, dark, math.Pi/2, 0, float64(i), scale, uint8(255-50*i))
+		die.Frames = append(die.Frames, rect(i, row))
+	}
+	sh.Animations["die"] = die
+	return img, sh
+}
+
+// Draw one enemy frame centred in img
+func drawEnemy(img *image.NRGBA, e enemySpec, c color.NRGBA, heading, step, bob, scale float64, alpha uint8) {
+	b := img.Bounds()
+	cx := float64(b.Min.X) + float64(b.Dx())/2
+	cy := float64(b.Min.Y) + float64(b.Dy())/2 + bob
+	r := e.radius * scale
+	c.A = alpha
+	dx, dy := math.Cos(heading), math.Sin(heading)
+
+	if e.flying {
+		// Wings flap with the step
+		span := r * 1.4
+		lift := 2 * step
+		wing := shade(c, 0.7)
+		for _, side := range []float64{-1, 1} {
+			fillTriangle(img,
+				cx+dx*r*0.7, cy+dy*r*0.7,
+				cx-dx*r*0.7, cy-dy*r*0.7,
+				cx-dy*side*(r+span), cy+dx*side*(r+span)-lift, wing)
+		}
+	} else {
+		// Feet swing back and forth along the heading
+		foot := shade(c, 0.55)
+		for _, side := range []float64{-1, 1} {
+			fx := cx - dy*side*r*0.5 + dx*side*step*r*0.35
+			fy := cy + r*0.75 - bob + dx*side*r*0.2 + dy*side*step*r*0.35
+			fillCircle(img, fx, fy, max(1.2, r*0.25), foot)
+		}
+	}
+
+	fillCircle(img, cx, cy, r, c)
+	fillCircle(img, cx-r*0.35, cy-r*0.35, r*0.35, shade(c, 1.25))
+	if e.decorate != nil {
+		e.decorate(img, cx, cy, r, c)
+	}
+	// Eyes, hidden when walking away from the viewer
+	if dy > -0.7 {
+		eye := color.NRGBA{0x13, 0x1a, 0x22, alpha}
+		ex, ey := cx+dx*r*0.45, cy+dy*r*0.2-r*0.2
+		for _, side := range []float64{-1, 1} {
+			fillCircle(img, ex-dy*side*r*0.3, ey+dx*side*r*0.15, max(0.7, r*0.12), eye)
+		}
+	}
+}
+
+func drawHelmet(img *image.NRGBA, cx, cy, r float64, c color.NRGBA) {
+	band := shade(c, 0.7)
+	fillRect(img, cx-r, cy-r*0.35, cx+r, cy-r*0.15, band)
+}
+
+func drawPlume(img *image.NRGBA, cx, cy, r float64, c color.NRGBA) {
+	drawHelmet(img, cx, cy, r, c)
+	plume := color.NRGBA{0xe0, 0x50, 0x40, c.A}
+	fillRect(img, cx-1, cy-r-2, cx+1, cy-r*0.35, plume)
+}
+
+func drawCrown(img *image.NRGBA, cx, cy, r float64, c color.NRGBA) {
+	gold := color.NRGBA{0xe7, 0xc3, 0x4b, c.A}
+	fillRect(img, cx-r*0.5, cy-r-1, cx+r*0.5, cy-r+2, gold)
+	for _, x := range []float64{-0.5, 0, 0.5} {
+		fillRect(img, cx+x*r-0.5, cy-r-3, cx+x*r+0.5, cy-r, gold)
+	}
+}
+
+// Rows: idle, attack
+func drawTowerSheet(t towerSpec) (*image.NRGBA, *sheet) {
+	const s = towerSize
+	img := image.NewNRGBA(image.Rect(0, 0, 3*s, 2*s))
+	c := hexColor(t.color)
+	sh := &sheet{Origin: [2]int{s / 2, s / 2}, Animations: map[string]*animation{}}
+	frame := func(col, row int) *image.NRGBA {
+		return img.SubImage(image.Rect(col*s, row*s, (col+1)*s, (row+1)*s)).(*image.NRGBA)
+	}
+
+	idle := &animation{Loop: true, Duration: 0.5}
+	for i := range 2 {
+		drawTower(frame(i, 0), t, c, 0.1*float64(i))
+		idle.Frames = append(idle.Frames, [4]int{i * s, 0, s, s})
+	}
+	sh.Animations["idle"] = idle
+
+	attack := &animation{Duration: 0.06}
+	for i, flash := range []float64{1, 0.6, 0.25} {
+		drawTower(frame(i, 1), t, c, flash)
+		attack.Frames = append(attack.Frames, [4]int{i * s, s, s, s})
+	}
+	sh.Animations["attack"] = attack
+	return img, sh
+}
+
+func drawTower(img *image.NRGBA, t towerSpec, c color.NRGBA, flash float64) {
+	b := img.Bounds()
+	x, y := float64(b.Min.X), float64(b.Min.Y)
+	stone := hexColor("6b7379")
+	fillRect(img, x+2, y+2, x+towerSize-2, y+towerSize-2, shade(stone, 0.6))
+	fillRect(img, x+3, y+3, x+towerSize-3, y+towerSize-3, stone)
+	t.top(img, flash, c)
+}
+
+func drawArrowTop(img *image.NRGBA, flash float64, c color.NRGBA) {
+	cx, cy := center(img)
+	fillCircle(img, cx, cy, 4.5, shade(c, 1+flash*0.4))
+	// Bow string
+	fillRect(img, cx-3, cy-0.5, cx+3, cy+0.5, shade(c, 0.5))
+}
+
+func drawCannonTop(img *image.NRGBA, flash float64, c color.NRGBA) {
+	cx, cy := center(img)
+	fillCircle(img, cx, cy, 4, c)
+	fillRect(img, cx-1.5, cy-6, cx+1.5, cy, shade(c, 0.7))
+	if flash > 0.5 {
+		fillCircle(img, cx, cy-6.5, 2, color.NRGBA{0xf2, 0xd4, 0x5c, 255})
+	}
+}
+
+func drawCrystalTop(img *image.NRGBA, flash float64, c color.NRGBA) {
+	cx, cy := center(img)
+	bright := shade(c, 1+flash*0.6)
+	fillTriangle(img, cx, cy-6, cx-4, cy, cx+4, cy, bright)
+	fillTriangle(img, cx, cy+5, cx-4, cy, cx+4, cy, shade(c, 0.8))
+}
+
+func center(img *image.NRGBA) (float64, float64) {
+	b := img.Bounds()
+	return float64(b.Min.X) + float64(b.Dx())/2, float64(b.Min.Y) + float64(b.Dy())/2
+}
+
+func hexColor(h string) color.NRGBA {
+	var c color.NRGBA
+	for i, p := range []*uint8{&c.R, &c.G, &c.B} {
+		var v uint8
+		for _, ch := range h[i*2 : i*2+2] {
+			v *= 16
+			switch {
+			case ch >= '0' && ch <= '9':
+				v += uint8(ch - '0')
+			default:
+				v += uint8(ch-'a') + 10
+			}
+		}
+		*p = v
+	}
+	c.A = 255
+	return c
+}
+
+// Scale the brightness of a color, keeping alpha
+func shade(c color.NRGBA, f float64) color.NRGBA {
+	s := func(v uint8) uint8 {
+		return uint8(min(255, float64(v)*f))
+	}
+	return color.NRGBA{s(c.R), s(c.G), s(c.B), c.A}
+}
+
+// Pixels whose centre lies inside the shape get the color, clipped to img
+func fillShape(img *image.NRGBA, minX, minY, maxX, maxY float64, inside func(x, y float64) bool, c color.NRGBA) {
+	b := img.Bounds()
+	for py := max(b.Min.Y, int(math.Floor(minY))); py < min(b.Max.Y, int(math.Ceil(maxY))); py++ {
+		for px := max(b.Min.X, int(math.Floor(minX))); px < min(b.Max.X, int(math.Ceil(maxX))); px++ {
+			if inside(float64(px)+0.5, float64(py)+0.5) {
+				img.SetNRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+func fillCircle(img *image.NRGBA, cx, cy, r float64, c color.NRGBA) {
+	fillShape(img, cx-r, cy-r, cx+r, cy+r, func(x, y float64) bool {
+		return (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r
+	}, c)
+}
+
+func fillRect(img *image.NRGBA, x0, y0, x1, y1 float64, c color.NRGBA) {
+	fillShape(img, x0, y0, x1, y1, func(x, y float64) bool { return true }, c)
+}
+
+func fillTriangle(img *image.NRGBA, x0, y0, x1, y1, x2, y2 float64, c color.NRGBA) {
+	edge := func(ax, ay, bx, by, x, y float64) float64 {
+		return (bx-ax)*(y-ay) - (by-ay)*(x-ax)
+	}
+	fillShape(img, min(x0, x1, x2), min(y0, y1, y2), max(x0, x1, x2), max(y0, y1, y2), func(x, y float64) bool {
+		e0, e1, e2 := edge(x0, y0, x1, y1, x, y), edge(x1, y1, x2, y2, x, y), edge(x2, y2, x0, y0, x, y)
+		return (e0 >= 0 && e1 >= 0 && e2 >= 0) || (e0 <= 0 && e1 <= 0 && e2 <= 0)
+	}, c)
+}
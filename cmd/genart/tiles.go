@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	"icosahedron.com/tower-defense/mapgen"
+)
+
+const tileSize = 16
+
+var (
+	grassColor = hexColor("5a9a3c")
+	dirtColor  = hexColor("c2a06b")
+	woodColor  = hexColor("8a6a3f")
+)
+
+// House size in tiles
+const houseWidth, houseHeight = 6, 5
+
+func writeTiles(dir string) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	tiles := map[string]*image.NRGBA{
+		"grass":         drawGrass(0),
+		"grass-flowers": drawGrass(1),
+		"grass-tufts":   drawGrass(2),
+		"grass-stones":  drawGrass(3),
+		"fence":         drawFence(),
+	}
+	// One road tile per neighbour mask, for the map generator and editor to autotile with
+	for _, m := range mapgen.EdgeMasks() {
+		tiles[fmt.Sprintf("road-%d", m)] = drawRoad(m)
+	}
+	house := drawHouse()
+	for y := range houseHeight {
+		for x := range houseWidth {
+			r := image.Rect(x*tileSize, y*tileSize, (x+1)*tileSize, (y+1)*tileSize)
+			t := image.NewNRGBA(image.Rect(0, 0, tileSize, tileSize))
+			for py := range tileSize {
+				for px := range tileSize {
+					t.SetNRGBA(px, py, house.NRGBAAt(r.Min.X+px, r.Min.Y+py))
+				}
+			}
+			tiles[fmt.Sprintf("house-%d-%d", y, x)] = t
+		}
+	}
+	for name, img := range tiles {
+		writePNG(filepath.Join(dir, name+".png"), img)
+	}
+}
+
+// Deterministic noise in [0, 1) so regenerating gives identical files
+func noise(x, y, seed int) float64 {
+	h := uint32(x)*374761393 + uint32(y)*668265263 + uint32(seed)*2246822519
+	h = (h ^ (h >> 13)) * 1274126177
+	return float64(h^(h>>16)) / (1 << 32)
+}
+
+func newTile() *image.NRGBA {
+	return image.NewNRGBA(image.Rect(0, 0, tileSize, tileSize))
+}
+
+// Speckled ground, the tiles tile seamlessly because the noise doesn't depend on the edge
+func fillGround(img *image.NRGBA, base color.NRGBA, seed int) {
+	for y := range tileSize {
+		for x := range tileSize {
+			c := base
+			switch n := noise(x, y, seed); {
+			case n < 0.12:
+				c = shade(base, 0.85)
+			case n > 0.9:
+				c = shade(base, 1.12)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
+
+// Plain grass and variants with flowers, tufts or stones
+func drawGrass(variant int) *image.NRGBA {
+	img := newTile()
+	fillGround(img, grassColor, 1)
+	switch variant {
+	case 1:
+		for i, p := range []image.Point{{3, 4}, {11, 3}, {7, 9}, {12, 12}, {4, 13}} {
+			c := []color.NRGBA{hexColor("f4f1e8"), hexColor("f2d45c"), hexColor("e58fb0")}[i%3]
+			img.SetNRGBA(p.X, p.Y, c)
+			img.SetNRGBA(p.X, p.Y+1, shade(grassColor, 0.7))
+		}
+	case 2:
+		for _, p := range []image.Point{{3, 5}, {10, 4}, {6, 11}, {12, 10}} {
+			for i := range 3 {
+				img.SetNRGBA(p.X+i-1, p.Y-(i%2), shade(grassColor, 0.65))
+				img.SetNRGBA(p.X+i-1, p.Y+1, shade(grassColor, 0.75))
+			}
+		}
+	case 3:
+		for _, p := range []image.Point{{4, 4}, {11, 7}, {6, 12}} {
+			fillRect(img, float64(p.X), float64(p.Y), float64(p.X+2), float64(p.Y+2), hexColor("9aa3a8"))
+			img.SetNRGBA(p.X, p.Y, hexColor("c9d0d4"))
+		}
+	}
+	return img
+}
+
+// Dirt with grass along the sides and corners the neighbour mask leaves out,
+// so roads of any shape join up
+func drawRoad(mask uint8) *image.NRGBA {
+	img := newTile()
+	fillGround(img, dirtColor, 2)
+	// Width of the grass along a side at a point on it, the same on every tile
+	// so edges line up where tiles meet
+	band := func(along int) int { return 2 + int(noise(0, along, 3)*2) }
+	sides := []struct {
+		bit uint8
+		// Distance in from the side and position along it
+		in func(x, y int) (int, int)
+	}{
+		{mapgen.North, func(x, y int) (int, int) { return y, x }},
+		{mapgen.East, func(x, y int) (int, int) { return tileSize - 1 - x, y }},
+		{mapgen.South, func(x, y int) (int, int) { return tileSize - 1 - y, x }},
+		{mapgen.West, func(x, y int) (int, int) { return x, y }},
+	}
+	corners := []struct {
+		bit, sides uint8
+		cx, cy     float64
+	}{
+		{mapgen.NorthEast, mapgen.North | mapgen.East, tileSize, 0},
+		{mapgen.SouthEast, mapgen.South | mapgen.East, tileSize, tileSize},
+		{mapgen.SouthWest, mapgen.South | mapgen.West, 0, tileSize},
+		{mapgen.NorthWest, mapgen.North | mapgen.West, 0, 0},
+	}
+	for y := range tileSize {
+		for x := range tileSize {
+			// Pixels from the grass, negative inside it
+			d := tileSize
+			for _, s := range sides {
+				if mask&s.bit == 0 {
+					in, along := s.in(x, y)
+					d = min(d, in-band(along))
+				}
+			}
+			// A bit of grass in corners between two road sides, the side
+			// bands already cover the others
+			for _, c := range corners {
+				if mask&c.bit == 0 && mask&c.sides == c.sides {
+					r := math.Hypot(float64(x)+0.5-c.cx, float64(y)+0.5-c.cy)
+					d = min(d, int(math.Floor(r))-3)
+				}
+			}
+			switch {
+			case d < 0:
+				img.SetNRGBA(x, y, grassColor)
+			case d == 0:
+				img.SetNRGBA(x, y, shade(dirtColor, 0.8))
+			}
+		}
+	}
+	return img
+}
+
+func drawFence() *image.NRGBA {
+	img := newTile()
+	fillGround(img, grassColor, 1)
+	fillRect(img, 0, 6, tileSize, 8, woodColor)
+	fillRect(img, 0, 11, tileSize, 13, woodColor)
+	for _, x := range []float64{2, 12} {
+		fillRect(img, x, 3, x+2, 15, shade(woodColor, 0.8))
+	}
+	return img
+}
+
+// The whole house in one image, cut into tiles afterwards
+func drawHouse() *image.NRGBA {
+	w, h := houseWidth*tileSize, houseHeight*tileSize
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	roof, wall := hexColor("a5493a"), hexColor("e3d3b0")
+
+	// Walls on the bottom two rows
+	wallTop := 3 * tileSize
+	fillRect(img, 4, float64(wallTop), float64(w-4), float64(h), wall)
+	fillRect(img, 4, float64(h-2), float64(w-4), float64(h), shade(wall, 0.75))
+	for _, x := range []float64{14, float64(w - 26)} {
+		fillRect(img, x, float64(wallTop+8), x+12, float64(wallTop+20), hexColor("4b687a"))
+		fillRect(img, x+5.5, float64(wallTop+8), x+6.5, float64(wallTop+20), woodColor)
+	}
+	// Door in the middle, where the road ends
+	fillRect(img, float64(w/2-7), float64(h-20), float64(w/2+7), float64(h), shade(woodColor, 0.8))
+	img.SetNRGBA(w/2+4, h-10, hexColor("e7c34b"))
+
+	// Roof with rows of shingles, narrowing towards the ridge
+	for y := range wallTop + 2 {
+		inset := max(0, (wallTop-y)/3-2)
+		for x := inset; x < w-inset; x++ {
+			c := roof
+			if (y+2)%6 == 0 || (x+(y/6)*4)%8 == 0 {
+				c = shade(roof, 0.8)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	fillRect(img, 0, float64(wallTop), float64(w), float64(wallTop+2), shade(roof, 0.6))
+	return img
+}
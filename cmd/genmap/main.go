@@ -0,0 +1,133 @@
+// Command genmap generates a map with a winding road and writes it in the
+// format the game loads. Waves and economy come from an existing level, so
+// a new map plays like it with a different layout:
+//
+//	go run ./cmd/genmap -seed 7 -id ridge -name Ridge -out assets/maps/ridge.json
+//
+// Generated maps can be checked for balance with tdsim before being added
+// to the campaign. With -retile it instead redraws the road of an existing
+// map along its path, picking edge tiles to fit what's next to each one and
+// updating where towers can go to match:
+//
+//	go run ./cmd/genmap -retile assets/maps/meadow.json -out assets/maps/meadow.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"icosahedron.com/tower-defense/mapgen"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// The part of the game's tileset files genmap needs
+type tilesetFile struct {
+	Tiles []string `json:"tiles"`
+}
+
+func main() {
+	width := flag.Int("width", 16, "map width in tiles, even")
+	height := flag.Int("height", 16, "map height in tiles, even")
+	seed := flag.Uint64("seed", 1, "seed of the layout, the level is played on it too")
+	id := flag.String("id", "generated", "level id")
+	name := flag.String("name", "Generated", "level name shown in the game")
+	templatePath := flag.String("template", "assets/maps/meadow.json", "level to take the waves, gold and lives from")
+	tilesetPath := flag.String("tileset", "assets/tilesets/meadow.json", "tileset to paint with")
+	minPath := flag.Float64("min-path", 20, "shortest road allowed, in tiles")
+	maxPath := flag.Float64("max-path", 60, "longest road allowed, in tiles, 0 for no limit")
+	minBuild := flag.Int("min-build", 40, "fewest tiles to build on allowed")
+	maxBuild := flag.Int("max-build", 0, "most tiles to build on allowed, 0 for no limit")
+	buildRange := flag.Float64("build-range", 3.5, "tiles further than this from the road can't be built on, 0 for no limit")
+	decoration := flag.Float64("decoration", 0.1, "share of grass tiles with a decoration")
+	attempts := flag.Int("attempts", 500, "layouts tried before giving up")
+	retile := flag.String("retile", "", "map to redraw the road of instead of generating one")
+	out := flag.String("out", "", "file to write the map to, standard output if empty")
+	flag.Parse()
+
+	tiles, err := loadTiles(*tilesetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *retile != "" {
+		l, err := loadLevel(*retile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := mapgen.RetileLevel(l, tiles); err != nil {
+			log.Fatalf("Can't retile %s: %v", *retile, err)
+		}
+		writeLevel(*out, l)
+		return
+	}
+	template, err := loadLevel(*templatePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l, err := mapgen.Generate(mapgen.Options{
+		Width:         *width,
+		Height:        *height,
+		Seed:          *seed,
+		Tiles:         tiles,
+		MinPathLength: *minPath,
+		MaxPathLength: *maxPath,
+		MinBuildable:  *minBuild,
+		MaxBuildable:  *maxBuild,
+		BuildRange:    *buildRange,
+		Decoration:    *decoration,
+		Attempts:      *attempts,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	l.ID = *id
+	l.Name = *name
+	l.StartingGold = template.StartingGold
+	l.StartingLives = template.StartingLives
+	l.Waves = template.Waves
+	l.FirstWaveDelay = template.FirstWaveDelay
+	l.WaveInterval = template.WaveInterval
+	l.EarlyCallBonus = template.EarlyCallBonus
+	writeLevel(*out, l)
+	log.Printf("Road %.0f tiles long", l.Branches[0].Path.Length())
+}
+
+func loadLevel(path string) (*sim.Level, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sim.LoadLevel(f)
+}
+
+// Write l to path, or standard output if it's empty
+func writeLevel(path string, l *sim.Level) {
+	w := os.Stdout
+	if path != "" {
+		var err error
+		if w, err = os.Create(path); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := sim.SaveLevel(w, l); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadTiles(path string) (mapgen.Tiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mapgen.Tiles{}, err
+	}
+	var f tilesetFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return mapgen.Tiles{}, err
+	}
+	return mapgen.TilesFromNames(f.Tiles)
+}
@@ -0,0 +1,211 @@
+// Command tdsim plays a level headlessly as fast as possible, once per seed,
+// and reports balance metrics as JSON or CSV. It plays with a build script
+// or one of the bots:
+//
+//	go run ./cmd/tdsim -map assets/maps/meadow.json -script cmd/tdsim/scripts/meadow.json -seeds 20 -format csv
+//	go run ./cmd/tdsim -map assets/maps/hollow.json -bot random -search 300 -save-plan hollow.json
+//
+// With -endless the level goes on with generated waves after its own, and
+// runs only end when lost or out of time. -difficulty and -modifiers play it
+// with the same rule changes the game offers before a level.
+//
+// Bots only build, so the hero is left out unless -hero is given, in which
+// case it stands at home and fights whatever comes by. With -call-early the
+// greedy bot calls waves early for the bonus whenever the map is clear.
+//
+// With -check it exits with status 1 when the level looks unwinnable or
+// trivial for the bot, so content changes can be checked automatically.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"icosahedron.com/tower-defense/bot"
+	"icosahedron.com/tower-defense/sim"
+)
+
+func main() {
+	mapPath := flag.String("map", "assets/maps/meadow.json", "level file to play")
+	botName := flag.String("bot", "script", "who plays: script, greedy or random")
+	scriptPath := flag.String("script", "", "build script for the script bot, nothing is built without one")
+	hero := flag.Bool("hero", false, "play with the hero, which bots leave standing at home")
+	callEarly := flag.Bool("call-early", false, "let the greedy bot call waves early whenever the map is clear")
+	iterations := flag.Int("search", 200, "build orders the random bot tries")
+	searchSeed := flag.Uint64("search-seed", 1, "seed of the random bot's choices")
+	planPath := flag.String("save-plan", "", "file to write the random bot's best build order to, as a build script")
+	seeds := flag.Int("seeds", 10, "number of runs, each with its own seed")
+	firstSeed := flag.Uint64("seed", 1, "seed of the first run, the others count up from it")
+	maxTime := flag.Float64("max-time", 3600, "seconds of game time before a run is stopped")
+	sample := flag.Float64("sample", 10, "seconds between points on the gold curve")
+	format := flag.String("format", "json", "output format, json or csv")
+	check := flag.Bool("check", false, "exit with status 1 if the level is unwinnable or trivial")
+	endless := flag.Bool("endless", false, "keep generating waves after the level's own")
+	difficulty := flag.String("difficulty", sim.DefaultDifficulty, "difficulty to play on")
+	modifierList := flag.String("modifiers", "", "comma separated modifiers to play with")
+	flag.Parse()
+
+	f, err := os.Open(*mapPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	level, err := sim.LoadLevel(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	modifiers := []string{*difficulty}
+	if *modifierList != "" {
+		modifiers = append(modifiers, strings.Split(*modifierList, ",")...)
+	}
+	content := sim.DefaultContent()
+	if !*hero {
+		// Bots don't steer the hero, so by default the towers are measured alone
+		content.Hero = nil
+	}
+	// Checked once, runs apply the modifiers again for their own seed
+	if _, _, err := sim.ApplyModifiers(level, content, modifiers); err != nil {
+		log.Fatal(err)
+	}
+	var runSeeds []uint64
+	for i := range *seeds {
+		runSeeds = append(runSeeds, *firstSeed+uint64(i))
+	}
+
+	// Fresh bots for every run, they keep state between ticks
+	var newBot func() bot.Bot
+	switch *botName {
+	case "script":
+		var actions []bot.Action
+		if *scriptPath != "" {
+			if actions, err = loadActions(*scriptPath); err != nil {
+				log.Fatal(err)
+			}
+		}
+		verbose := true
+		newBot = func() bot.Bot {
+			b := bot.NewScript(actions, verbose)
+			// Warn about broken actions once rather than for every seed
+			verbose = false
+			return b
+		}
+	case "greedy":
+		newBot = func() bot.Bot { return &bot.Greedy{CallEarly: *callEarly} }
+	case "random":
+		actions := bot.RandomSearch(level, content, bot.SearchOptions{
+			Iterations: *iterations,
+			Seeds:      runSeeds,
+			Modifiers:  modifiers,
+			Endless:    *endless,
+			Seed:       *searchSeed,
+			MaxTime:    *maxTime,
+		})
+		if *planPath != "" {
+			if err := saveActions(*planPath, actions); err != nil {
+				log.Fatal(err)
+			}
+		}
+		newBot = func() bot.Bot { return bot.NewScript(actions, false) }
+	default:
+		log.Fatalf("Unknown bot %q", *botName)
+	}
+
+	r := report{Level: level.ID, Bot: *botName, Endless: *endless, Modifiers: modifiers}
+	for _, seed := range runSeeds {
+		seeded := *level
+		seeded.Seed = seed
+		// The tower types left by some modifiers depend on the seed
+		l, c, _ := sim.ApplyModifiers(&seeded, content, modifiers)
+		var w *sim.World
+		if *endless {
+			w = sim.NewEndlessWorld(l, c)
+		} else {
+			w = sim.NewWorld(l, c)
+		}
+		r.Runs = append(r.Runs, run(w, newBot(), *maxTime, *sample))
+	}
+	r.summarize()
+
+	switch *format {
+	case "json":
+		err = writeJSON(os.Stdout, r)
+	case "csv":
+		err = writeCSV(os.Stdout, r, *sample)
+	default:
+		log.Fatalf("Unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *check && !*endless && r.Verdict != verdictOK {
+		log.Printf("Level %s looks %s", level.ID, r.Verdict)
+		os.Exit(1)
+	}
+}
+
+func loadActions(path string) ([]bot.Action, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return bot.LoadActions(f)
+}
+
+func saveActions(path string, actions []bot.Action) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bot.SaveActions(f, actions); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Play one game to the end, or until maxTime seconds have passed
+func run(w *sim.World, b bot.Bot, maxTime, sample float64) runReport {
+	r := runReport{Seed: w.Level().Seed, LeakedByType: map[string]int{}}
+	sampleTicks := max(1, int(sample*sim.TicksPerSecond))
+	r.GoldCurve = append(r.GoldCurve, w.Gold())
+	// Towers as they were last seen. Sales happen first thing in a tick, so
+	// that is everything sold ones did.
+	towers := map[int]towerReport{}
+	var built []int
+	bot.Play(w, b, maxTime, func(w *sim.World) {
+		if w.Tick()%sampleTicks == 0 {
+			r.GoldCurve = append(r.GoldCurve, w.Gold())
+		}
+		for _, t := range w.Towers() {
+			if _, ok := towers[t.ID]; !ok {
+				built = append(built, t.ID)
+			}
+			towers[t.ID] = newTowerReport(t)
+		}
+		for _, e := range w.Events() {
+			if e.Kind == sim.EventLeak {
+				r.LeakedByType[e.Type]++
+			}
+		}
+	})
+
+	r.Won = w.Won()
+	r.Lives = w.Lives()
+	r.Leaked = w.Leaked()
+	r.Gold = w.Gold()
+	r.Waves = w.WavesStarted()
+	r.Time = w.Time()
+	if r.Won {
+		t := w.Time()
+		r.TimeToClear = &t
+	}
+	for _, id := range built {
+		t := towers[id]
+		t.Sold = w.Tower(id) == nil
+		r.Towers = append(r.Towers, t)
+	}
+	return r
+}
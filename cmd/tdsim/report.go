@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+type towerReport struct {
+	ID     int      `json:"id"`
+	Type   string   `json:"type"`
+	Cell   sim.Cell `json:"cell"`
+	Level  int      `json:"level"`
+	Damage float64  `json:"damage"`
+	Kills  int      `json:"kills"`
+	// Sold before the end, with what it did until then
+	Sold bool `json:"sold,omitempty"`
+}
+
+func newTowerReport(t *sim.Tower) towerReport {
+	return towerReport{
+		ID:     t.ID,
+		Type:   t.Type.ID,
+		Cell:   t.Cell,
+		Level:  t.Level,
+		Damage: t.DamageDealt,
+		Kills:  t.Kills,
+	}
+}
+
+// Column name of a tower in the CSV output, the same tower across seeds shares it
+func (t towerReport) label() string {
+	return fmt.Sprintf("damage %s@%d:%d", t.Type, t.Cell.X, t.Cell.Y)
+}
+
+type runReport struct {
+	Seed         uint64         `json:"seed"`
+	Won          bool           `json:"won"`
+	Lives        int            `json:"lives"`
+	Leaked       int            `json:"leaked"`
+	LeakedByType map[string]int `json:"leakedByType"`
+	Gold         int            `json:"gold"`
+	// Waves started, the measure of how far endless runs got
+	Waves int `json:"waves"`
+	// Game time in seconds when the run ended
+	Time float64 `json:"time"`
+	// Game time in seconds the level was won at, nil when it wasn't
+	TimeToClear *float64 `json:"timeToClear"`
+	// Every tower built, in the order they were
+	Towers []towerReport `json:"towers"`
+	// Gold every sample interval from the start
+	GoldCurve []int `json:"goldCurve"`
+}
+
+// Enum of how a level played for the bot over all seeds
+type verdict string
+
+const (
+	verdictOK = verdict("ok")
+	// No run was won
+	verdictUnwinnable = verdict("unwinnable")
+	// Every run was won without losing a life
+	verdictTrivial = verdict("trivial")
+)
+
+type report struct {
+	Level   string `json:"level"`
+	Bot     string `json:"bot"`
+	Endless bool   `json:"endless"`
+	// Difficulty first, then the other modifiers
+	Modifiers []string `json:"modifiers"`
+	// Left out for endless runs, which are never won
+	Verdict verdict `json:"verdict,omitempty"`
+	WinRate float64 `json:"winRate"`
+	// Averages over all runs
+	MeanLeaked float64 `json:"meanLeaked"`
+	MeanLives  float64 `json:"meanLives"`
+	// Average over the runs that were won, 0 when none were
+	MeanTimeToClear float64     `json:"meanTimeToClear"`
+	MeanWaves       float64     `json:"meanWaves"`
+	Runs            []runReport `json:"runs"`
+}
+
+func (r *report) summarize() {
+	if len(r.Runs) == 0 {
+		return
+	}
+	wins, flawless := 0, 0
+	for _, run := range r.Runs {
+		if run.Won && run.Leaked == 0 {
+			flawless++
+		}
+		r.MeanLeaked += float64(run.Leaked)
+		r.MeanLives += float64(run.Lives)
+		r.MeanWaves += float64(run.Waves)
+		if run.TimeToClear != nil {
+			wins++
+			r.MeanTimeToClear += *run.TimeToClear
+		}
+	}
+	n := float64(len(r.Runs))
+	r.WinRate = float64(wins) / n
+	r.MeanLeaked /= n
+	r.MeanLives /= n
+	r.MeanWaves /= n
+	if wins > 0 {
+		r.MeanTimeToClear /= float64(wins)
+	}
+	switch {
+	case r.Endless:
+	case wins == 0:
+		r.Verdict = verdictUnwinnable
+	case flawless == len(r.Runs):
+		r.Verdict = verdictTrivial
+	default:
+		r.Verdict = verdictOK
+	}
+}
+
+func writeJSON(w io.Writer, r report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// One row per run, with a damage column per tower and a column per point of the gold curve
+func writeCSV(w io.Writer, r report, sample float64) error {
+	var towers []string
+	seen := map[string]bool{}
+	samples := 0
+	for _, run := range r.Runs {
+		for _, t := range run.Towers {
+			if !seen[t.label()] {
+				seen[t.label()] = true
+				towers = append(towers, t.label())
+			}
+		}
+		samples = max(samples, len(run.GoldCurve))
+	}
+
+	out := csv.NewWriter(w)
+	header := []string{"seed", "won", "lives", "leaked", "gold", "waves", "timeToClear"}
+	header = append(header, towers...)
+	for i := range samples {
+		header = append(header, "gold@"+strconv.FormatFloat(float64(i)*sample, 'f', -1, 64)+"s")
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for _, run := range r.Runs {
+		cleared := ""
+		if run.TimeToClear != nil {
+			cleared = strconv.FormatFloat(*run.TimeToClear, 'f', 2, 64)
+		}
+		row := []string{
+			strconv.FormatUint(run.Seed, 10),
+			strconv.FormatBool(run.Won),
+			strconv.Itoa(run.Lives),
+			strconv.Itoa(run.Leaked),
+			strconv.Itoa(run.Gold),
+			strconv.Itoa(run.Waves),
+			cleared,
+		}
+		damage := map[string]float64{}
+		for _, t := range run.Towers {
+			damage[t.label()] += t.Damage
+		}
+		for _, label := range towers {
+			row = append(row, strconv.FormatFloat(damage[label], 'f', 1, 64))
+		}
+		for i := range samples {
+			v := ""
+			if i < len(run.GoldCurve) {
+				v = strconv.Itoa(run.GoldCurve[i])
+			}
+			row = append(row, v)
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"image/color"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+const (
+	// Most numbers shown at once, the oldest one is reused when more are needed
+	maxCombatTexts = 96
+	// Lifetime in simulation ticks
+	combatTextTicks = sim.TicksPerSecond
+	// Distance risen over the lifetime, in tiles
+	combatTextRise = 0.8
+)
+
+var (
+	damageTextColor = hexToColor("dff4ff")
+	critTextColor   = hexToColor("e7c34b")
+	goldTextColor   = hexToColor("f2d45c")
+)
+
+type floatingText struct {
+	label string
+	face  font.Face
+	color color.Color
+	// World position it started at, in tiles
+	pos sim.Vec2
+	age int
+}
+
+// Numbers that float up from enemies when they take damage or pay out gold.
+// The texts live in a fixed pool and labels are cached, so busy fights don't
+// allocate every frame.
+type CombatText struct {
+	face     font.Face
+	critFace font.Face
+	texts    [maxCombatTexts]floatingText
+	// Index of the next slot to fill, it wraps around over the oldest text
+	next         int
+	damageLabels map[int]string
+	goldLabels   map[int]string
+	op           ebiten.DrawImageOptions
+}
+
+func NewCombatText() *CombatText {
+	face, _ := loadFont(14)
+	critFace, _ := loadFont(20)
+	c := &CombatText{
+		face:         face,
+		critFace:     critFace,
+		damageLabels: map[int]string{},
+		goldLabels:   map[int]string{},
+	}
+	for i := range c.texts {
+		c.texts[i].age = combatTextTicks
+	}
+	return c
+}
+
+func cachedLabel(cache map[int]string, n int, prefix string) string {
+	if l, ok := cache[n]; ok {
+		return l
+	}
+	l := prefix + strconv.Itoa(n)
+	cache[n] = l
+	return l
+}
+
+func (c *CombatText) add(label string, face font.Face, clr color.Color, pos sim.Vec2) {
+	t := &c.texts[c.next]
+	t.label = label
+	t.face = face
+	t.color = clr
+	t.pos = pos
+	t.age = 0
+	c.next = (c.next + 1) % len(c.texts)
+}
+
+// Start texts for the events of the last simulation tick
+func (c *CombatText) AddEvents(events []sim.Event) {
+	for _, e := range events {
+		switch e.Kind {
+		case sim.EventDamage:
+			n := max(1, int(e.Amount+0.5))
+			if e.Crit {
+				c.add(cachedLabel(c.damageLabels, n, ""), c.critFace, critTextColor, e.Pos)
+			} else {
+				c.add(cachedLabel(c.damageLabels, n, ""), c.face, damageTextColor, e.Pos)
+			}
+		case sim.EventGold:
+			c.add(cachedLabel(c.goldLabels, int(e.Amount), "+"), c.face, goldTextColor, e.Pos.Add(sim.Vec2{Y: -0.3}))
+		case sim.EventHeroLevel:
+			// Rare enough not to need a cache
+			c.add("Level "+strconv.Itoa(int(e.Amount)), c.critFace, critTextColor, e.Pos.Add(sim.Vec2{Y: -0.6}))
+		}
+	}
+}
+
+// Age the texts by one simulation tick, so they freeze while the game is paused
+func (c *CombatText) Step() {
+	for i := range c.texts {
+		if c.texts[i].age < combatTextTicks {
+			c.texts[i].age++
+		}
+	}
+}
+
+func (c *CombatText) Draw(screen *ebiten.Image, camera *Camera) {
+	for i := range c.texts {
+		t := &c.texts[i]
+		if t.age >= combatTextTicks {
+			continue
+		}
+		progress := float64(t.age) / combatTextTicks
+		x, y := camera.WorldToScreen(mgl32.Vec2{
+			float32(t.pos.X * tileSize),
+			float32((t.pos.Y - progress*combatTextRise) * tileSize),
+		})
+		w := font.MeasureString(t.face, t.label).Ceil()
+
+		op := &c.op
+		op.GeoM.Reset()
+		op.ColorScale.Reset()
+		op.GeoM.Translate(x-float64(w)/2, y)
+		op.ColorScale.ScaleWithColor(t.color)
+		// Fade out over the second half of the lifetime
+		op.ColorScale.ScaleAlpha(float32(min(1, 2-2*progress)))
+		text.DrawWithOptions(screen, t.label, t.face, op)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// How far a stick has to be pushed to be captured as a binding
+const axisCaptureThreshold = 0.7
+
+// Button in the controls window showing one action's bindings for one device class
+type bindingButton struct {
+	action  Action
+	gamepad bool
+	button  *widget.Button
+}
+
+// A binding button waiting for the player to press the new input
+type rebindCapture struct {
+	bindingButton
+	status *widget.Text
+}
+
+// Open the controls window, triggered from the main menu
+func openControlsMenu(g *Game) {
+	res, _ := newUIResources()
+	var rw widget.RemoveWindowFunc
+	var window *widget.Window
+
+	titleFace, _ := loadFont(24)
+	face, _ := loadFont(16)
+
+	titleBar := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(widget.GridLayoutOpts.Columns(2), widget.GridLayoutOpts.Stretch([]bool{true, false}, []bool{true}), widget.GridLayoutOpts.Padding(widget.Insets{
+			Left:   30,
+			Right:  5,
+			Top:    6,
+			Bottom: 5,
+		}))))
+
+	titleBar.AddChild(widget.NewText(
+		widget.TextOpts.Text("Controls", titleFace, res.textInput.color.Idle),
+		widget.TextOpts.Position(widget.TextPositionStart, widget.TextPositionCenter),
+	))
+
+	titleBar.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("X", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			g.closeWindow()
+		}),
+		widget.ButtonOpts.TabOrder(99),
+	))
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(
+			widget.NewGridLayout(
+				widget.GridLayoutOpts.Columns(3),
+				widget.GridLayoutOpts.Stretch([]bool{true, false, false}, nil),
+				widget.GridLayoutOpts.Padding(res.panel.padding),
+				// Tight rows so every action fits without scrolling
+				widget.GridLayoutOpts.Spacing(10, 2),
+			),
+		),
+	)
+
+	status := widget.NewText(
+		widget.TextOpts.Text("Click a binding, then press the new input. Delete clears it.", face, res.text.idleColor),
+	)
+
+	for _, h := range []string{"Action", "Keyboard / Mouse", "Gamepad"} {
+		c.AddChild(widget.NewText(widget.TextOpts.Text(h, face, res.text.disabledColor)))
+	}
+
+	g.bindingButtons = nil
+	for _, action := range allActions() {
+		c.AddChild(widget.NewText(
+			widget.TextOpts.Text(action.Label(), face, res.label.text.Idle),
+			widget.TextOpts.Position(widget.TextPositionStart, widget.TextPositionCenter),
+		))
+		for _, gamepad := range []bool{false, true} {
+			var b *widget.Button
+			b = widget.NewButton(
+				widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.MinSize(170, 0)),
+				widget.ButtonOpts.Image(res.button.image),
+				widget.ButtonOpts.TextPadding(widget.Insets{Left: 8, Right: 8, Top: 1, Bottom: 1}),
+				widget.ButtonOpts.Text(bindingsLabel(g.input.Bindings(action), gamepad), face, res.button.text),
+				widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+					g.refreshControlsMenu()
+					g.rebind = &rebindCapture{
+						bindingButton: bindingButton{action: action, gamepad: gamepad, button: b},
+						status:        status,
+					}
+					b.Text().Label = "..."
+					if gamepad {
+						status.Label = fmt.Sprintf("Press a gamepad button or push a stick for %s", action.Label())
+					} else {
+						status.Label = fmt.Sprintf("Press a key, or click outside this window, for %s", action.Label())
+					}
+				}),
+			)
+			c.AddChild(b)
+			g.bindingButtons = append(g.bindingButtons, bindingButton{action: action, gamepad: gamepad, button: b})
+		}
+	}
+
+	content := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.Insets{Bottom: 15}),
+		)),
+	)
+	content.AddChild(c)
+	statusRow := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.Insets{Left: 30, Right: 30}),
+		)),
+	)
+	statusRow.AddChild(status)
+	content.AddChild(statusRow)
+
+	window = widget.NewWindow(
+		widget.WindowOpts.Modal(),
+		widget.WindowOpts.Contents(content),
+		widget.WindowOpts.TitleBar(titleBar, 30),
+		widget.WindowOpts.Draggable(),
+	)
+	windowSize := input.GetWindowSize()
+	r := image.Rect(0, 0, 640, 880)
+	r = r.Add(image.Point{(windowSize.X - r.Dx()) / 2, (windowSize.Y - r.Dy()) / 2})
+	window.SetLocation(r)
+
+	g.window = ControlsMenu
+	rw = g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		g.rebind = nil
+		g.bindingButtons = nil
+		g.window = MainMenu
+		g.saveSettings()
+		rw()
+	})
+}
+
+// Joins the bindings from one device class for display on a binding button
+func bindingsLabel(bindings []Binding, gamepad bool) string {
+	var labels []string
+	for _, b := range bindings {
+		if b.IsGamepad() == gamepad {
+			labels = append(labels, b.Label())
+		}
+	}
+	if len(labels) == 0 {
+		return "-"
+	}
+	return strings.Join(labels, ", ")
+}
+
+// Waits for the input that should be bound while a binding button is capturing
+func (g *Game) updateRebind() {
+	c := g.rebind
+	if inpututil.IsKeyJustPressed(ebiten.KeyDelete) {
+		g.input.Clear(c.action, c.gamepad)
+		c.status.Label = fmt.Sprintf("Cleared %s", c.action.Label())
+		g.finishRebind()
+		return
+	}
+
+	b, ok := captureBinding(c.gamepad)
+	if !ok {
+		return
+	}
+	conflicts := g.input.Rebind(c.action, b)
+	if len(conflicts) > 0 {
+		var labels []string
+		for _, a := range conflicts {
+			labels = append(labels, a.Label())
+		}
+		c.status.Label = fmt.Sprintf("%s was bound to %s, moved to %s", b.Label(), strings.Join(labels, ", "), c.action.Label())
+	} else {
+		c.status.Label = fmt.Sprintf("Bound %s to %s", b.Label(), c.action.Label())
+	}
+	g.finishRebind()
+}
+
+func (g *Game) finishRebind() {
+	g.rebind = nil
+	// Conflicting actions lost a binding, so every button label may be stale
+	g.refreshControlsMenu()
+	g.saveSettings()
+}
+
+func (g *Game) refreshControlsMenu() {
+	for _, b := range g.bindingButtons {
+		b.button.Text().Label = bindingsLabel(g.input.Bindings(b.action), b.gamepad)
+	}
+}
+
+// Returns the first input pressed this frame from the requested device class
+func captureBinding(gamepad bool) (Binding, bool) {
+	if !gamepad {
+		if keys := inpututil.AppendJustPressedKeys(nil); len(keys) > 0 {
+			return keyBinding(keys[0]), true
+		}
+		for mb := ebiten.MouseButton0; mb <= ebiten.MouseButtonMax; mb++ {
+			// Left clicks on the UI are used to operate the controls window itself
+			if mb == ebiten.MouseButtonLeft && input.UIHovered {
+				continue
+			}
+			if inpututil.IsMouseButtonJustPressed(mb) {
+				return mouseBinding(mb), true
+			}
+		}
+		return Binding{}, false
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if buttons := inpututil.AppendJustPressedStandardGamepadButtons(id, nil); len(buttons) > 0 {
+			return padBinding(buttons[0]), true
+		}
+		for a := ebiten.StandardGamepadAxis(0); a <= ebiten.StandardGamepadAxisMax; a++ {
+			v := ebiten.StandardGamepadAxisValue(id, a)
+			if math.Abs(v) > axisCaptureThreshold {
+				sign := 1
+				if v < 0 {
+					sign = -1
+				}
+				return axisBinding(a, sign), true
+			}
+		}
+	}
+	return Binding{}, false
+}
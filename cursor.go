@@ -0,0 +1,87 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// Seconds a cursor direction has to be held before the cursor starts gliding
+	cursorRepeatDelay = 0.25
+	// Tiles / second the cursor glides at when fully pushed
+	cursorSpeed = 8
+)
+
+// Tile snapped cursor used to pick tiles without a mouse
+type Cursor struct {
+	// Position in tiles, the highlighted tile is the one containing it
+	position mgl32.Vec2
+	held     float32
+	visible  bool
+}
+
+func (c *Cursor) Tile() image.Point {
+	return image.Point{int(math.Floor(float64(c.position[0]))), int(math.Floor(float64(c.position[1])))}
+}
+
+// Place the cursor in the middle of a tile
+func (c *Cursor) SetTile(p image.Point) {
+	c.position = mgl32.Vec2{float32(p.X) + 0.5, float32(p.Y) + 0.5}
+}
+
+// Moves the cursor one tile per press, gliding when a direction is held.
+// bounds is the size of the map in tiles.
+func (c *Cursor) Update(deltaTime float32, in *InputMap, bounds image.Point) (moved bool) {
+	dir := mgl32.Vec2{
+		float32(in.Value(ActionCursorRight) - in.Value(ActionCursorLeft)),
+		float32(in.Value(ActionCursorDown) - in.Value(ActionCursorUp)),
+	}
+	if dir[0] == 0 && dir[1] == 0 {
+		c.held = 0
+		return false
+	}
+
+	var step mgl32.Vec2
+	if in.JustPressed(ActionCursorLeft) {
+		step[0]--
+	}
+	if in.JustPressed(ActionCursorRight) {
+		step[0]++
+	}
+	if in.JustPressed(ActionCursorUp) {
+		step[1]--
+	}
+	if in.JustPressed(ActionCursorDown) {
+		step[1]++
+	}
+
+	if step[0] != 0 || step[1] != 0 {
+		c.SetTile(c.Tile().Add(image.Point{int(step[0]), int(step[1])}))
+		c.held = 0
+	} else {
+		c.held += deltaTime
+		if c.held < cursorRepeatDelay {
+			return false
+		}
+		c.position = c.position.Add(dir.Mul(cursorSpeed * deltaTime))
+	}
+
+	c.position[0] = mgl32.Clamp(c.position[0], 0, float32(bounds.X)-0.001)
+	c.position[1] = mgl32.Clamp(c.position[1], 0, float32(bounds.Y)-0.001)
+	return true
+}
+
+func (c *Cursor) Draw(screen *ebiten.Image, camera *Camera) {
+	if !c.visible {
+		return
+	}
+	t := c.Tile()
+	x, y := camera.WorldToScreen(mgl32.Vec2{float32(t.X * tileSize), float32(t.Y * tileSize)})
+	size := float32(tileSize * camera.zoom)
+	vector.StrokeRect(screen, float32(x), float32(y), size, size, 2, color.White, false)
+}
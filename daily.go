@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"time"
+
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/ebitenui/ebitenui/widget"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Today's challenge, picked from the campaign levels
+func (g *Game) todaysChallenge() sim.Challenge {
+	var ids []string
+	for _, l := range g.campaign {
+		ids = append(ids, l.ID)
+	}
+	return sim.DailyChallenge(time.Now(), ids, sim.DefaultContent())
+}
+
+// Replace the running game with a daily challenge
+func (g *Game) startChallenge(ch sim.Challenge) error {
+	for i, l := range g.campaign {
+		if l.ID != ch.Level {
+			continue
+		}
+		level, content, err := ch.Apply(l, sim.DefaultContent())
+		if err != nil {
+			return err
+		}
+		g.startWorld(i, sim.NewWorld(level, content), nil)
+		g.daily = &ch
+		return nil
+	}
+	return fmt.Errorf("challenge is for unknown level %s", ch.Level)
+}
+
+// Lines explaining where the points of a score came from
+func scoreLines(b sim.ScoreBreakdown) []string {
+	return []string{
+		fmt.Sprintf("Waves %d   Lives %d   Gold %d", b.Waves, b.Lives, b.Gold),
+		fmt.Sprintf("Modifiers x%.1f   Score %d", b.Multiplier, b.Total),
+	}
+}
+
+// Today's map and modifiers, the best result so far and a button to play it
+func openDaily(g *Game) {
+	res, _ := newUIResources()
+	face, _ := loadFont(20)
+	smallFace, _ := loadFont(16)
+	ch := g.todaysChallenge()
+	content := sim.DefaultContent()
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(res.panel.padding),
+			widget.RowLayoutOpts.Spacing(10),
+		)),
+	)
+
+	name := ch.Level
+	for _, l := range g.campaign {
+		if l.ID == ch.Level {
+			name = l.Name
+		}
+	}
+	c.AddChild(widget.NewText(widget.TextOpts.Text(fmt.Sprintf("%s: %s", ch.Date, name), face, res.label.text.Idle)))
+	for _, id := range ch.Modifiers {
+		m := content.Modifiers[id]
+		c.AddChild(widget.NewText(widget.TextOpts.Text(fmt.Sprintf("%s: %s (+%.1f score)", m.Name, m.Description, m.ScoreBonus), smallFace, res.text.idleColor)))
+	}
+	if r, ok := g.profile.Daily(ch.Date); ok {
+		outcome := "not won yet"
+		if r.Won {
+			outcome = "won"
+		}
+		c.AddChild(widget.NewText(widget.TextOpts.Text(fmt.Sprintf("Best of %d attempts, %s", r.Attempts, outcome), face, res.label.text.Idle)))
+		for _, l := range scoreLines(r.Best) {
+			c.AddChild(widget.NewText(widget.TextOpts.Text(l, smallFace, res.text.idleColor)))
+		}
+	}
+
+	status := widget.NewText(widget.TextOpts.Text("", face, res.label.text.Idle))
+	c.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Play", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			// Closes this window when it works
+			if err := g.startChallenge(ch); err != nil {
+				log.Println("Failed to start the daily challenge:", err)
+				status.Label = "Failed to start"
+			}
+		}),
+	))
+	c.AddChild(status)
+
+	window := widget.NewWindow(
+		widget.WindowOpts.Modal(),
+		widget.WindowOpts.Contents(c),
+		widget.WindowOpts.TitleBar(newWindowTitleBar(g, res, "Daily Challenge", face), 30),
+		widget.WindowOpts.Draggable(),
+	)
+	windowSize := input.GetWindowSize()
+	r := image.Rect(0, 0, 560, 340)
+	r = r.Add(image.Point{(windowSize.X - r.Dx()) / 2, (windowSize.Y - r.Dy()) / 2})
+	window.SetLocation(r)
+
+	previous := g.window
+	g.window = Daily
+	rw := g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		g.window = previous
+		rw()
+	})
+}
@@ -0,0 +1,521 @@
+// Package editor changes levels the way the game's level editor does, with
+// every change undoable. It knows nothing of input or drawing, so the editor
+// screen only turns clicks into calls here.
+package editor
+
+import (
+	"math"
+	"slices"
+
+	"icosahedron.com/tower-defense/mapgen"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Steps of undo kept, older ones are forgotten
+const maxUndo = 200
+
+// A level being edited with its undo and redo history
+type Editor struct {
+	level *sim.Level
+	// Tiles the road brush paints with
+	road mapgen.EdgeTiles
+	// Every change makes a new copy of the level, so the ones on the stacks
+	// stay as they were
+	undo, redo []*sim.Level
+	// Set between Begin and End, changes made then undo together
+	inStroke bool
+	// The level as it was before the stroke is already on the undo stack
+	strokeSaved bool
+	// The level as it was loaded or last saved
+	saved *sim.Level
+}
+
+// Edit a copy of l, painting roads with the given tiles
+func New(l *sim.Level, road mapgen.EdgeTiles) *Editor {
+	e := &Editor{level: cloneLevel(l), road: road}
+	e.saved = e.level
+	return e
+}
+
+// Blank level of grass with a road running straight up the middle, a start
+// for new maps
+func NewLevel(id string, width, height, grass int) *sim.Level {
+	l := &sim.Level{
+		ID:     id,
+		Name:   id,
+		Width:  width,
+		Height: height,
+		Layers: [][]int{make([]int, width*height), make([]int, width*height)},
+		Branches: []sim.Branch{{Path: sim.Path{
+			{X: float64(width / 2), Y: float64(height) + 0.5},
+			{X: float64(width / 2), Y: -0.5},
+		}}},
+		StartingGold:   150,
+		StartingLives:  20,
+		FirstWaveDelay: 20,
+		WaveInterval:   30,
+		EarlyCallBonus: 1,
+		Waves:          []sim.Wave{{Groups: []sim.WaveGroup{{Enemy: "grunt", Count: 8, Interval: 1}}}},
+	}
+	for i := range l.Layers[0] {
+		l.Layers[0][i] = grass
+	}
+	l.Buildable = sim.BuildableTiles(l)
+	return l
+}
+
+// The level as edited so far. It belongs to the editor and changes with it.
+func (e *Editor) Level() *sim.Level {
+	return e.level
+}
+
+// Whether the level changed since it was loaded or last saved
+func (e *Editor) Changed() bool {
+	return e.level != e.saved
+}
+
+func (e *Editor) MarkSaved() {
+	e.saved = e.level
+	// The rest of a stroke changes a new copy, leaving the saved one alone
+	e.strokeSaved = false
+}
+
+// Start a stroke, like dragging a brush, so everything changed until End
+// undoes in one step
+func (e *Editor) Begin() {
+	e.inStroke = true
+	e.strokeSaved = false
+}
+
+func (e *Editor) End() {
+	e.inStroke = false
+}
+
+func (e *Editor) CanUndo() bool {
+	return len(e.undo) > 0
+}
+
+func (e *Editor) CanRedo() bool {
+	return len(e.redo) > 0
+}
+
+func (e *Editor) Undo() bool {
+	if len(e.undo) == 0 {
+		return false
+	}
+	e.redo = append(e.redo, e.level)
+	e.level = e.undo[len(e.undo)-1]
+	e.undo = e.undo[:len(e.undo)-1]
+	e.strokeSaved = false
+	return true
+}
+
+func (e *Editor) Redo() bool {
+	if len(e.redo) == 0 {
+		return false
+	}
+	e.undo = append(e.undo, e.level)
+	e.level = e.redo[len(e.redo)-1]
+	e.redo = e.redo[:len(e.redo)-1]
+	e.strokeSaved = false
+	return true
+}
+
+// Apply a change to the level, saving it for undo first. Tiles towers can go
+// on are worked out again, keeping the ones marked by hand.
+func (e *Editor) modify(change func(l *sim.Level)) {
+	if !e.inStroke || !e.strokeSaved {
+		e.undo = append(e.undo, e.level)
+		e.level = cloneLevel(e.level)
+		if len(e.undo) > maxUndo {
+			e.undo = slices.Delete(e.undo, 0, len(e.undo)-maxUndo)
+		}
+		e.strokeSaved = e.inStroke
+	}
+	e.redo = nil
+
+	l := e.level
+	derived := sim.BuildableTiles(l)
+	change(l)
+	marked := map[int]bool{}
+	for i, b := range l.Buildable {
+		if b != derived[i] {
+			marked[i] = b
+		}
+	}
+	l.Buildable = sim.BuildableTiles(l)
+	for i, b := range marked {
+		l.Buildable[i] = b
+	}
+}
+
+func (e *Editor) index(c sim.Cell) int {
+	return c.Y*e.level.Width + c.X
+}
+
+// Put tile id on a layer, 0 clears it
+func (e *Editor) SetTile(layer int, c sim.Cell, id int) {
+	if !e.level.Contains(c) || layer < 0 || layer >= len(e.level.Layers) || e.level.Layers[layer][e.index(c)] == id {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		l.Layers[layer][e.index(c)] = id
+	})
+}
+
+// Whether a road tile is on the layer at c
+func (e *Editor) IsRoad(layer int, c sim.Cell) bool {
+	if !e.level.Contains(c) || layer < 0 || layer >= len(e.level.Layers) {
+		return false
+	}
+	id := e.level.Layers[layer][e.index(c)]
+	for _, r := range e.road {
+		if id == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Add or remove road at c on a layer, picking the edge tiles of it and its
+// neighbours again so the road joins up
+func (e *Editor) SetRoad(layer int, c sim.Cell, on bool) {
+	if !e.level.Contains(c) || layer < 0 || layer >= len(e.level.Layers) || e.IsRoad(layer, c) == on {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		road := make([]bool, l.Width*l.Height)
+		for i := range road {
+			road[i] = e.IsRoad(layer, sim.Cell{X: i % l.Width, Y: i / l.Width})
+		}
+		road[e.index(c)] = on
+		if on {
+			// Make room for the road, RetileRoad leaves other tiles alone
+			l.Layers[layer][e.index(c)] = 0
+		}
+		mapgen.RetileRoad(l.Layers[layer], road, l.Width, l.Height, e.road)
+	})
+}
+
+// Let towers go on c or not, whatever is on it
+func (e *Editor) SetBuildable(c sim.Cell, buildable bool) {
+	if !e.level.Contains(c) || e.level.Buildable[e.index(c)] == buildable {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		l.Buildable[e.index(c)] = buildable
+	})
+}
+
+// A point of the road, the Index'th of a branch
+type Waypoint struct {
+	Branch, Index int
+}
+
+func (e *Editor) point(w Waypoint) sim.Vec2 {
+	return e.level.Branches[w.Branch].Path[w.Index]
+}
+
+func (e *Editor) valid(w Waypoint) bool {
+	return w.Branch >= 0 && w.Branch < len(e.level.Branches) && w.Index >= 0 && w.Index < len(e.level.Branches[w.Branch].Path)
+}
+
+// The waypoint within r of p, the closest if there are several. Where
+// branches join any of their points there will do.
+func (e *Editor) WaypointAt(p sim.Vec2, r float64) (Waypoint, bool) {
+	return e.closestWaypoint(p, r, func(sim.Vec2) bool { return true })
+}
+
+func (e *Editor) closestWaypoint(p sim.Vec2, r float64, keep func(sim.Vec2) bool) (Waypoint, bool) {
+	var best Waypoint
+	found := false
+	for b, branch := range e.level.Branches {
+		for i, w := range branch.Path {
+			if d := w.Sub(p).Len(); d <= r && keep(w) && (!found || d < e.point(best).Sub(p).Len()) {
+				best, found = Waypoint{b, i}, true
+			}
+		}
+	}
+	return best, found
+}
+
+// Move a waypoint along with every other at the same spot, so branches
+// joined there stay joined
+func (e *Editor) MoveWaypoint(w Waypoint, p sim.Vec2) {
+	if !e.valid(w) || e.point(w) == p {
+		return
+	}
+	from := e.point(w)
+	e.modify(func(l *sim.Level) {
+		for _, b := range l.Branches {
+			for i := range b.Path {
+				if b.Path[i] == from {
+					b.Path[i] = p
+				}
+			}
+		}
+	})
+}
+
+// Add a waypoint at p to the segment of the road closest to it
+func (e *Editor) InsertWaypoint(p sim.Vec2) Waypoint {
+	best := Waypoint{0, 1}
+	bestDistance := math.Inf(1)
+	for b, branch := range e.level.Branches {
+		for i := 1; i < len(branch.Path); i++ {
+			if d := branch.Path[i-1 : i+1].DistanceTo(p); d < bestDistance {
+				best, bestDistance = Waypoint{b, i}, d
+			}
+		}
+	}
+	e.modify(func(l *sim.Level) {
+		b := &l.Branches[best.Branch]
+		b.Path = slices.Insert(b.Path, best.Index, p)
+	})
+	return best
+}
+
+// Remove a waypoint between the ends of its branch
+func (e *Editor) RemoveWaypoint(w Waypoint) {
+	if !e.valid(w) || w.Index == 0 || w.Index == len(e.level.Branches[w.Branch].Path)-1 {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		b := &l.Branches[w.Branch]
+		b.Path = slices.Delete(b.Path, w.Index, w.Index+1)
+	})
+}
+
+// Index of the spawn within r of p, in the order of Level.Spawns
+func (e *Editor) SpawnAt(p sim.Vec2, r float64) (int, bool) {
+	return closest(e.level.Spawns(), p, r)
+}
+
+// Index of the exit within r of p, in the order of Level.Exits
+func (e *Editor) ExitAt(p sim.Vec2, r float64) (int, bool) {
+	return closest(e.level.Exits(), p, r)
+}
+
+func closest(points []sim.Vec2, p sim.Vec2, r float64) (int, bool) {
+	best, found := 0, false
+	for i, q := range points {
+		if d := q.Sub(p).Len(); d <= r && (!found || d < points[best].Sub(p).Len()) {
+			best, found = i, true
+		}
+	}
+	return best, found
+}
+
+// Waypoint of a spawn or exit, to move it with MoveWaypoint
+func (e *Editor) SpawnWaypoint(i int) Waypoint {
+	spawn := e.level.Spawns()[i]
+	for b, branch := range e.level.Branches {
+		if branch.Path[0] == spawn {
+			return Waypoint{b, 0}
+		}
+	}
+	return Waypoint{}
+}
+
+func (e *Editor) ExitWaypoint(i int) Waypoint {
+	exit := e.level.Exits()[i]
+	for b, branch := range e.level.Branches {
+		if branch.Path[len(branch.Path)-1] == exit {
+			return Waypoint{b, len(branch.Path) - 1}
+		}
+	}
+	return Waypoint{}
+}
+
+// Add a spawn at p with a branch merging into the road at its closest
+// waypoint, other than a spawn. It comes last in the order of spawns.
+// Returns the new spawn's waypoint.
+func (e *Editor) AddSpawn(p sim.Vec2) Waypoint {
+	spawns := e.level.Spawns()
+	join, ok := e.closestWaypoint(p, math.Inf(1), func(w sim.Vec2) bool { return !slices.Contains(spawns, w) })
+	if !ok {
+		return Waypoint{Branch: -1}
+	}
+	e.modify(func(l *sim.Level) {
+		to := splitAt(l, join)
+		l.Branches = append(l.Branches, sim.Branch{Path: sim.Path{p, to}})
+	})
+	return Waypoint{len(e.level.Branches) - 1, 0}
+}
+
+// Add an exit at p with a branch forking off the road at its closest
+// waypoint. Returns the new exit's waypoint.
+func (e *Editor) AddExit(p sim.Vec2) Waypoint {
+	join, ok := e.WaypointAt(p, math.Inf(1))
+	if !ok {
+		return Waypoint{Branch: -1}
+	}
+	e.modify(func(l *sim.Level) {
+		from := splitAt(l, join)
+		l.Branches = append(l.Branches, sim.Branch{Path: sim.Path{from, p}})
+	})
+	return Waypoint{len(e.level.Branches) - 1, 1}
+}
+
+// Split a branch in two at one of its inner points so other branches can
+// join it there, and return the point. The second half goes last.
+func splitAt(l *sim.Level, w Waypoint) sim.Vec2 {
+	b := &l.Branches[w.Branch]
+	p := b.Path[w.Index]
+	if w.Index > 0 && w.Index < len(b.Path)-1 {
+		rest := sim.Branch{Path: slices.Clone(b.Path[w.Index:])}
+		b.Path = b.Path[:w.Index+1]
+		l.Branches = append(l.Branches, rest)
+	}
+	return p
+}
+
+// Remove a spawn and the branches only enemies from it walk. The last spawn
+// stays. Wave groups coming in there move to the first spawn.
+func (e *Editor) RemoveSpawn(i int) {
+	spawns := e.level.Spawns()
+	if i < 0 || i >= len(spawns) || len(spawns) < 2 {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		remove := func(b sim.Branch) bool { return b.Path[0] == spawns[i] }
+		for {
+			l.Branches = slices.DeleteFunc(l.Branches, remove)
+			// Branches left without a way in
+			orphaned := l.Spawns()
+			remove = func(b sim.Branch) bool {
+				return slices.Contains(orphaned, b.Path[0]) && !slices.Contains(spawns, b.Path[0])
+			}
+			if !slices.ContainsFunc(l.Branches, remove) {
+				break
+			}
+		}
+		for _, w := range l.Waves {
+			for j := range w.Groups {
+				switch g := &w.Groups[j]; {
+				case g.Spawn == i:
+					g.Spawn = 0
+				case g.Spawn > i:
+					g.Spawn--
+				}
+			}
+		}
+	})
+}
+
+// Remove an exit and the branches that only lead to it. The last exit stays.
+func (e *Editor) RemoveExit(i int) {
+	exits := e.level.Exits()
+	if i < 0 || i >= len(exits) || len(exits) < 2 {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		last := func(b sim.Branch) sim.Vec2 { return b.Path[len(b.Path)-1] }
+		remove := func(b sim.Branch) bool { return last(b) == exits[i] }
+		for {
+			l.Branches = slices.DeleteFunc(l.Branches, remove)
+			// Branches left without a way out
+			stranded := l.Exits()
+			remove = func(b sim.Branch) bool {
+				return slices.Contains(stranded, last(b)) && !slices.Contains(exits, last(b))
+			}
+			if !slices.ContainsFunc(l.Branches, remove) {
+				break
+			}
+		}
+	})
+}
+
+// Index of the branch passing within r of p, the closest if there are several
+func (e *Editor) BranchAt(p sim.Vec2, r float64) (int, bool) {
+	best, found := 0, false
+	for i, b := range e.level.Branches {
+		if d := b.Path.DistanceTo(p); d <= r && (!found || d < e.level.Branches[best].Path.DistanceTo(p)) {
+			best, found = i, true
+		}
+	}
+	return best, found
+}
+
+// Set how likely enemies at a fork are to take a branch, relative to the
+// others there
+func (e *Editor) SetWeight(branch int, weight float64) {
+	if branch < 0 || branch >= len(e.level.Branches) || weight < 0 || e.level.Branches[branch].Weight == weight {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		l.Branches[branch].Weight = weight
+	})
+}
+
+// Add a copy of wave i after it, or a wave of grunts if there are none
+func (e *Editor) AddWave(i int) {
+	e.modify(func(l *sim.Level) {
+		w := sim.Wave{Groups: []sim.WaveGroup{{Enemy: "grunt", Count: 8, Interval: 1}}}
+		if i >= 0 && i < len(l.Waves) {
+			w = sim.Wave{Groups: slices.Clone(l.Waves[i].Groups)}
+		}
+		l.Waves = slices.Insert(l.Waves, min(max(i+1, 0), len(l.Waves)), w)
+	})
+}
+
+func (e *Editor) RemoveWave(i int) {
+	if i < 0 || i >= len(e.level.Waves) {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		l.Waves = slices.Delete(l.Waves, i, i+1)
+	})
+}
+
+// Add a copy of the last group of wave i to it
+func (e *Editor) AddGroup(wave int) {
+	if wave < 0 || wave >= len(e.level.Waves) {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		g := sim.WaveGroup{Enemy: "grunt", Count: 5, Interval: 1}
+		if groups := l.Waves[wave].Groups; len(groups) > 0 {
+			g = groups[len(groups)-1]
+		}
+		l.Waves[wave].Groups = append(l.Waves[wave].Groups, g)
+	})
+}
+
+func (e *Editor) RemoveGroup(wave, group int) {
+	if wave < 0 || wave >= len(e.level.Waves) || group < 0 || group >= len(e.level.Waves[wave].Groups) {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		l.Waves[wave].Groups = slices.Delete(l.Waves[wave].Groups, group, group+1)
+	})
+}
+
+func (e *Editor) SetGroup(wave, group int, g sim.WaveGroup) {
+	if wave < 0 || wave >= len(e.level.Waves) || group < 0 || group >= len(e.level.Waves[wave].Groups) || e.level.Waves[wave].Groups[group] == g {
+		return
+	}
+	e.modify(func(l *sim.Level) {
+		l.Waves[wave].Groups[group] = g
+	})
+}
+
+// Copy of l sharing nothing it could change with it
+func cloneLevel(l *sim.Level) *sim.Level {
+	c := *l
+	c.Layers = make([][]int, len(l.Layers))
+	for i, layer := range l.Layers {
+		c.Layers[i] = slices.Clone(layer)
+	}
+	c.Buildable = slices.Clone(l.Buildable)
+	c.Branches = make([]sim.Branch, len(l.Branches))
+	for i, b := range l.Branches {
+		c.Branches[i] = sim.Branch{Path: slices.Clone(b.Path), Weight: b.Weight}
+	}
+	c.Waves = make([]sim.Wave, len(l.Waves))
+	for i, w := range l.Waves {
+		c.Waves[i] = sim.Wave{Groups: slices.Clone(w.Groups)}
+	}
+	c.Modifiers = slices.Clone(l.Modifiers)
+	return &c
+}
@@ -0,0 +1,46 @@
+package editor
+
+import (
+	"testing"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+func TestChangedFollowsUndoAndRedo(t *testing.T) {
+	e := New(NewLevel("test", 8, 8, 1), nil)
+	check := func(step string, want bool) {
+		t.Helper()
+		if got := e.Changed(); got != want {
+			t.Fatalf("%s: changed = %v, want %v", step, got, want)
+		}
+	}
+	check("loaded", false)
+
+	e.SetTile(0, sim.Cell{X: 1, Y: 1}, 2)
+	check("edited", true)
+	e.Undo()
+	check("undone back to the loaded level", false)
+	e.Redo()
+	check("redone", true)
+
+	e.MarkSaved()
+	check("saved", false)
+	e.SetTile(0, sim.Cell{X: 2, Y: 1}, 2)
+	check("edited after saving", true)
+	e.Undo()
+	check("undone back to the saved level", false)
+	e.Undo()
+	check("undone past the saved level", true)
+	e.Redo()
+	check("redone to the saved level", false)
+
+	// A stroke saved halfway leaves the saved level alone when it goes on
+	e.Begin()
+	e.SetTile(0, sim.Cell{X: 3, Y: 1}, 2)
+	e.MarkSaved()
+	e.SetTile(0, sim.Cell{X: 4, Y: 1}, 2)
+	e.End()
+	check("stroke went on after saving", true)
+	e.Undo()
+	check("undone to the middle of the stroke", false)
+}
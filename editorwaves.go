@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strconv"
+
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/ebitenui/ebitenui/widget"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Edit the groups of one wave of the map in the editor at a time. Everything
+// changed while the window is open undoes in one step.
+func openEditorWaves(g *Game, wave int) {
+	res, _ := newUIResources()
+	face, _ := loadFont(18)
+	e := g.editor
+	content := sim.DefaultContent()
+	waves := e.edit.Level().Waves
+	spawns := len(e.edit.Level().Spawns())
+	wave = max(0, min(wave, len(waves)-1))
+
+	// Built again after anything that changes the rows shown
+	reopen := func(wave int) {
+		g.closeWindow()
+		openEditorWaves(g, wave)
+	}
+	button := func(label string, clicked func()) *widget.Button {
+		return widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 10, Right: 10, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.Text(label, face, res.button.text),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				clicked()
+			}),
+		)
+	}
+	row := func() *widget.Container {
+		return widget.NewContainer(widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(8),
+		)))
+	}
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(res.panel.padding),
+			widget.RowLayoutOpts.Spacing(10),
+		)),
+	)
+
+	nav := row()
+	c.AddChild(nav)
+	prev := button("<", func() { reopen(wave - 1) })
+	prev.GetWidget().Disabled = wave == 0
+	nav.AddChild(prev)
+	label := "No waves"
+	if len(waves) > 0 {
+		label = fmt.Sprintf("Wave %d of %d", wave+1, len(waves))
+	}
+	nav.AddChild(widget.NewText(
+		widget.TextOpts.Text(label, face, res.text.idleColor),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Position: widget.RowLayoutPositionCenter})),
+	))
+	next := button(">", func() { reopen(wave + 1) })
+	next.GetWidget().Disabled = wave >= len(waves)-1
+	nav.AddChild(next)
+	nav.AddChild(button("Add Wave", func() {
+		e.edit.AddWave(wave)
+		reopen(wave + 1)
+	}))
+	if len(waves) > 0 {
+		nav.AddChild(button("Remove Wave", func() {
+			e.edit.RemoveWave(wave)
+			reopen(wave)
+		}))
+	}
+
+	if len(waves) > 0 {
+		header := row()
+		for _, h := range []string{"Enemy", "Spawn", "Count", "Delay", "Interval"} {
+			header.AddChild(widget.NewText(
+				widget.TextOpts.Text(h, face, res.text.disabledColor),
+				widget.TextOpts.WidgetOpts(widget.WidgetOpts.MinSize(110, 0)),
+			))
+		}
+		c.AddChild(header)
+
+		for i, group := range waves[wave].Groups {
+			r := row()
+			c.AddChild(r)
+			cycle := func(label string, next func(wg *sim.WaveGroup)) {
+				r.AddChild(widget.NewButton(
+					widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.MinSize(110, 0)),
+					widget.ButtonOpts.Image(res.button.image),
+					widget.ButtonOpts.TextPadding(widget.Insets{Left: 10, Right: 10, Top: 4, Bottom: 4}),
+					widget.ButtonOpts.Text(label, face, res.button.text),
+					widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+						wg := e.edit.Level().Waves[wave].Groups[i]
+						next(&wg)
+						e.edit.SetGroup(wave, i, wg)
+						reopen(wave)
+					}),
+				))
+			}
+			// Cycle through the enemy types in the order they are met
+			cycle(enemyName(content, group.Enemy), func(wg *sim.WaveGroup) {
+				next := (slices.Index(content.EnemyOrder, wg.Enemy) + 1) % len(content.EnemyOrder)
+				wg.Enemy = content.EnemyOrder[next]
+			})
+			cycle(strconv.Itoa(group.Spawn+1), func(wg *sim.WaveGroup) {
+				wg.Spawn = (wg.Spawn + 1) % max(1, spawns)
+			})
+
+			// Numbers that don't parse or are out of range leave the group as it is
+			field := func(value string, set func(wg *sim.WaveGroup, s string) bool) {
+				in := widget.NewTextInput(
+					widget.TextInputOpts.WidgetOpts(widget.WidgetOpts.MinSize(110, 0)),
+					widget.TextInputOpts.Image(res.textInput.image),
+					widget.TextInputOpts.Color(res.textInput.color),
+					widget.TextInputOpts.Padding(res.textInput.padding),
+					widget.TextInputOpts.Face(face),
+					widget.TextInputOpts.CaretOpts(widget.CaretOpts.Size(face, 2)),
+					widget.TextInputOpts.ChangedHandler(func(args *widget.TextInputChangedEventArgs) {
+						wg := e.edit.Level().Waves[wave].Groups[i]
+						if set(&wg, args.InputText) {
+							e.edit.SetGroup(wave, i, wg)
+						}
+					}),
+				)
+				in.SetText(value)
+				r.AddChild(in)
+			}
+			field(strconv.Itoa(group.Count), func(wg *sim.WaveGroup, s string) bool {
+				n, err := strconv.Atoi(s)
+				wg.Count = n
+				return err == nil && n > 0
+			})
+			field(formatSeconds(group.Delay), func(wg *sim.WaveGroup, s string) bool {
+				v, err := strconv.ParseFloat(s, 64)
+				wg.Delay = v
+				return err == nil && v >= 0
+			})
+			field(formatSeconds(group.Interval), func(wg *sim.WaveGroup, s string) bool {
+				v, err := strconv.ParseFloat(s, 64)
+				wg.Interval = v
+				return err == nil && v >= 0
+			})
+			r.AddChild(button("X", func() {
+				e.edit.RemoveGroup(wave, i)
+				reopen(wave)
+			}))
+		}
+		c.AddChild(button("Add Group", func() {
+			e.edit.AddGroup(wave)
+			reopen(wave)
+		}))
+	}
+
+	window := widget.NewWindow(
+		widget.WindowOpts.Modal(),
+		widget.WindowOpts.Contents(c),
+		widget.WindowOpts.TitleBar(newWindowTitleBar(g, res, "Waves", face), 30),
+		widget.WindowOpts.Draggable(),
+	)
+	windowSize := input.GetWindowSize()
+	groups := 0
+	if len(waves) > 0 {
+		groups = len(waves[wave].Groups)
+	}
+	r := image.Rect(0, 0, 740, 190+46*groups)
+	r = r.Add(image.Point{(windowSize.X - r.Dx()) / 2, (windowSize.Y - r.Dy()) / 2})
+	window.SetLocation(r)
+
+	e.edit.Begin()
+	g.window = EditorWaves
+	rw := g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		e.edit.End()
+		g.window = None
+		rw()
+	})
+}
+
+func enemyName(content *sim.Content, id string) string {
+	if t, ok := content.Enemies[id]; ok {
+		return t.Name
+	}
+	return id
+}
+
+// Seconds without trailing zeros
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
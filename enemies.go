@@ -0,0 +1,66 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type enemyStyle struct {
+	color color.Color
+	// Radius in game units
+	radius float32
+}
+
+var enemyStyles = map[string]enemyStyle{
+	"grunt":  {color: hexToColor("c0503a"), radius: 5},
+	"runner": {color: hexToColor("e7c34b"), radius: 4},
+	"brute":  {color: hexToColor("7a3b8f"), radius: 7},
+	"bat":    {color: hexToColor("6fa8dc"), radius: 4},
+	"knight": {color: hexToColor("a7b1b7"), radius: 6},
+	"ogre":   {color: hexToColor("4e7d32"), radius: 10},
+}
+
+func getEnemyStyle(id string) enemyStyle {
+	if style, ok := enemyStyles[id]; ok {
+		return style
+	}
+	return enemyStyle{color: color.White, radius: 5}
+}
+
+func (g *Game) drawEnemies(screen *ebiten.Image) {
+	for _, e := range g.world.Enemies() {
+		style := getEnemyStyle(e.Type.ID)
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(e.Pos.X * tileSize), float32(e.Pos.Y * tileSize)})
+		// Types without a sprite sheet are drawn as plain circles
+		if !g.sprites.Draw(screen, &g.camera, g.sprites.enemies[e.ID], e.Pos, g.world.Time()) {
+			vector.DrawFilledCircle(screen, float32(x), float32(y), style.radius*float32(g.camera.zoom), style.color, true)
+		}
+		if g.settings.showHealthBars && e.HP < e.Type.HP {
+			drawHealthBar(screen, float32(x), float32(y)-(style.radius+3)*float32(g.camera.zoom), style.radius*2*float32(g.camera.zoom), float32(e.HP/e.Type.HP))
+		}
+	}
+}
+
+var (
+	healthBarBackground = hexToColor("2a3944")
+	healthHighColor     = hexToColor("5fb04a")
+	healthMidColor      = hexToColor("e7c34b")
+	healthLowColor      = hexToColor("c0503a")
+)
+
+// Bar of the given width centered on x, with its bottom edge at y
+func drawHealthBar(screen *ebiten.Image, x, y, width, fraction float32) {
+	const height = 4
+	clr := healthHighColor
+	switch {
+	case fraction < 0.3:
+		clr = healthLowColor
+	case fraction < 0.6:
+		clr = healthMidColor
+	}
+	vector.DrawFilledRect(screen, x-width/2-1, y-height-1, width+2, height+2, healthBarBackground, false)
+	vector.DrawFilledRect(screen, x-width/2, y-height, width*fraction, height, clr, false)
+}
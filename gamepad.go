@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/ebitenui/ebitenui/widget"
+)
+
+// Widgets such as buttons and checkboxes that can be activated programmatically
+type clicker interface {
+	Click()
+}
+
+// Lets a gamepad operate the open windows: the d-pad moves focus between
+// widgets, Select activates the focused one and Cancel closes the window
+func (g *Game) updateWindowNavigation() {
+	if g.input.JustPressed(ActionCursorUp) || g.input.JustPressed(ActionCursorLeft) {
+		g.ui.ChangeFocus(widget.FOCUS_PREVIOUS)
+	}
+	if g.input.JustPressed(ActionCursorDown) || g.input.JustPressed(ActionCursorRight) {
+		g.ui.ChangeFocus(widget.FOCUS_NEXT)
+	}
+
+	// Mouse clicks are already handled by the widgets themselves
+	if g.input.JustPressed(ActionSelect) && g.input.FromGamepad(ActionSelect) {
+		if c, ok := g.ui.GetFocusedWidget().(clicker); ok {
+			c.Click()
+		}
+	}
+	if g.input.JustPressed(ActionCancel) && g.input.FromGamepad(ActionCancel) {
+		g.closeWindow()
+	}
+}
+
+// Give the first widget focus so the gamepad has something to navigate from
+func (g *Game) focusFirstWidget() {
+	if !g.ui.HasFocus() {
+		g.ui.ChangeFocus(widget.FOCUS_NEXT)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// Distance in pixels a pointer can wander before a press becomes a drag.
+	// Fingers are less precise than a mouse so they get more slack.
+	mouseSlop = 4
+	touchSlop = 16
+	// Updates a pointer has to stay still to count as a long press
+	longPressTicks = 30
+)
+
+type trackedPointer struct {
+	start image.Point
+	last  image.Point
+	// Position at the end of the previous update
+	prev     image.Point
+	downTick int
+	touch    bool
+	// The press went down on the UI, so the gamefield ignores it
+	overUI      bool
+	dragging    bool
+	longPressed bool
+	// Another pointer was down during the press, so it can't be a tap
+	multi bool
+}
+
+func (p *trackedPointer) slop() float64 {
+	if p.touch {
+		return touchSlop
+	}
+	return mouseSlop
+}
+
+// Turns raw pointer events into taps, drags, pinches and long presses
+type Gestures struct {
+	source PointerSource
+	// Reports whether the UI is under the primary pointer
+	overUI   func() bool
+	pointers map[int]*trackedPointer
+	// Ids of the pointers that are down, in the order they went down
+	order  []int
+	tick   int
+	events []PointerEvent
+
+	tapped      bool
+	tapAt       image.Point
+	longPressed bool
+	longPressAt image.Point
+	drag        image.Point
+	pinched     bool
+	pinchCenter image.Point
+	pinchScale  float64
+}
+
+func NewGestures(source PointerSource, overUI func() bool) *Gestures {
+	return &Gestures{
+		source:   source,
+		overUI:   overUI,
+		pointers: map[int]*trackedPointer{},
+	}
+}
+
+func (g *Gestures) Update() {
+	g.tick++
+	g.tapped = false
+	g.longPressed = false
+	g.drag = image.Point{}
+	g.pinched = false
+	defer func() {
+		for _, p := range g.pointers {
+			p.prev = p.last
+		}
+	}()
+
+	g.events = g.source.AppendEvents(g.events[:0])
+	for _, e := range g.events {
+		switch e.Kind {
+		case PointerDown:
+			p := &trackedPointer{start: e.Position, last: e.Position, prev: e.Position, downTick: g.tick, touch: e.Touch}
+			g.pointers[e.ID] = p
+			g.order = append(g.order, e.ID)
+			if len(g.order) > 1 {
+				for _, id := range g.order {
+					g.pointers[id].multi = true
+				}
+			}
+		case PointerMove:
+			if p, ok := g.pointers[e.ID]; ok {
+				p.last = e.Position
+			}
+		case PointerUp:
+			p, ok := g.pointers[e.ID]
+			if !ok {
+				continue
+			}
+			p.last = e.Position
+			if !p.overUI && !p.dragging && !p.longPressed && !p.multi {
+				g.tapped = true
+				g.tapAt = p.last
+			}
+			delete(g.pointers, e.ID)
+			for i, id := range g.order {
+				if id == e.ID {
+					g.order = append(g.order[:i], g.order[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	if len(g.order) > 0 {
+		primary := g.pointers[g.order[0]]
+		// The UI only learns what is under the pointer after it moved there,
+		// so keep checking until the press turns into a gesture
+		if !primary.dragging && !primary.longPressed && g.overUI() {
+			primary.overUI = true
+		}
+	}
+
+	if pinch := g.pinchPointers(); pinch != nil {
+		g.updatePinch(
+			[2]image.Point{pinch[0].prev, pinch[1].prev},
+			[2]image.Point{pinch[0].last, pinch[1].last})
+		return
+	}
+
+	if len(g.order) == 1 {
+		p := g.pointers[g.order[0]]
+		if p.overUI || p.longPressed {
+			return
+		}
+		if !p.dragging && distance(p.start, p.last) > p.slop() {
+			p.dragging = true
+			// Include the slop so the drag doesn't lag behind the pointer
+			p.prev = p.start
+		}
+		if p.dragging {
+			g.drag = p.last.Sub(p.prev)
+			return
+		}
+		if !p.multi && g.tick-p.downTick >= longPressTicks {
+			p.longPressed = true
+			g.longPressed = true
+			g.longPressAt = p.last
+		}
+	}
+}
+
+// Returns the first two pointers if two or more are down and none started on the UI
+func (g *Gestures) pinchPointers() []*trackedPointer {
+	if len(g.order) < 2 {
+		return nil
+	}
+	a, b := g.pointers[g.order[0]], g.pointers[g.order[1]]
+	if a.overUI || b.overUI {
+		return nil
+	}
+	return []*trackedPointer{a, b}
+}
+
+func (g *Gestures) updatePinch(before, after [2]image.Point) {
+	d0 := distance(before[0], before[1])
+	d1 := distance(after[0], after[1])
+	if d0 == 0 || d1 == 0 {
+		return
+	}
+	c0 := before[0].Add(before[1]).Div(2)
+	c1 := after[0].Add(after[1]).Div(2)
+	g.pinched = true
+	g.pinchScale = d1 / d0
+	g.pinchCenter = c1
+	// Moving both fingers together pans like a drag
+	g.drag = c1.Sub(c0)
+}
+
+// Position of a tap that ended during the last update
+func (g *Gestures) Tap() (image.Point, bool) {
+	return g.tapAt, g.tapped
+}
+
+// Position of a press that was held still long enough during the last update
+func (g *Gestures) LongPress() (image.Point, bool) {
+	return g.longPressAt, g.longPressed
+}
+
+// How far a dragging pointer moved during the last update, in screen pixels
+func (g *Gestures) Drag() image.Point {
+	return g.drag
+}
+
+// Change in distance between two fingers during the last update, as a scale factor around center
+func (g *Gestures) Pinch() (center image.Point, scale float64, ok bool) {
+	return g.pinchCenter, g.pinchScale, g.pinched
+}
+
+func distance(a, b image.Point) float64 {
+	d := a.Sub(b)
+	return math.Hypot(float64(d.X), float64(d.Y))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Gestures fed by a synthetic source, with the UI never under the pointer
+// unless overUI is set
+type gestureRig struct {
+	source   *SyntheticPointerSource
+	gestures *Gestures
+	overUI   bool
+}
+
+func newGestureRig() *gestureRig {
+	r := &gestureRig{source: &SyntheticPointerSource{}}
+	r.gestures = NewGestures(r.source, func() bool { return r.overUI })
+	return r
+}
+
+// Run one update with the given events
+func (r *gestureRig) step(events ...PointerEvent) {
+	r.source.Push(events...)
+	r.gestures.Update()
+}
+
+func mouse(kind PointerEventKind, x, y int) PointerEvent {
+	return PointerEvent{ID: mousePointerID, Kind: kind, Position: image.Pt(x, y)}
+}
+
+func touch(id int, kind PointerEventKind, x, y int) PointerEvent {
+	return PointerEvent{ID: id, Kind: kind, Position: image.Pt(x, y), Touch: true}
+}
+
+func TestTap(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []PointerEvent
+		tap    bool
+	}{
+		{"click", []PointerEvent{mouse(PointerDown, 10, 10), mouse(PointerUp, 10, 10)}, true},
+		{"mouse within slop", []PointerEvent{mouse(PointerDown, 10, 10), mouse(PointerMove, 14, 10), mouse(PointerUp, 14, 10)}, true},
+		{"mouse past slop", []PointerEvent{mouse(PointerDown, 10, 10), mouse(PointerMove, 15, 10), mouse(PointerUp, 15, 10)}, false},
+		{"touch within slop", []PointerEvent{touch(0, PointerDown, 10, 10), touch(0, PointerMove, 26, 10), touch(0, PointerUp, 26, 10)}, true},
+		{"touch past slop", []PointerEvent{touch(0, PointerDown, 10, 10), touch(0, PointerMove, 27, 10), touch(0, PointerUp, 27, 10)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newGestureRig()
+			var tapped bool
+			var at image.Point
+			// One event per update, so moves are seen before the release
+			for _, e := range tt.events {
+				r.step(e)
+				if p, ok := r.gestures.Tap(); ok {
+					tapped, at = true, p
+				}
+			}
+			if tapped != tt.tap {
+				t.Fatalf("tapped = %v, want %v", tapped, tt.tap)
+			}
+			if want := tt.events[len(tt.events)-1].Position; tapped && at != want {
+				t.Errorf("tap at %v, want %v", at, want)
+			}
+		})
+	}
+}
+
+func TestTapOverUI(t *testing.T) {
+	r := newGestureRig()
+	r.overUI = true
+	r.step(mouse(PointerDown, 10, 10))
+	r.overUI = false
+	r.step(mouse(PointerUp, 10, 10))
+	if _, ok := r.gestures.Tap(); ok {
+		t.Error("a press that started on the UI was a tap")
+	}
+}
+
+func TestDrag(t *testing.T) {
+	r := newGestureRig()
+	r.step(mouse(PointerDown, 10, 10))
+	r.step(mouse(PointerMove, 14, 10))
+	if d := r.gestures.Drag(); d != (image.Point{}) {
+		t.Fatalf("drag within slop = %v, want none", d)
+	}
+	// Crossing the slop drags by the whole distance from the press
+	r.step(mouse(PointerMove, 16, 10))
+	if d, want := r.gestures.Drag(), image.Pt(6, 0); d != want {
+		t.Fatalf("drag = %v, want %v", d, want)
+	}
+	r.step(mouse(PointerMove, 16, 13))
+	if d, want := r.gestures.Drag(), image.Pt(0, 3); d != want {
+		t.Fatalf("drag = %v, want %v", d, want)
+	}
+	r.step()
+	if d := r.gestures.Drag(); d != (image.Point{}) {
+		t.Fatalf("drag without moving = %v, want none", d)
+	}
+	r.step(mouse(PointerUp, 16, 13))
+	if _, ok := r.gestures.Tap(); ok {
+		t.Error("a drag ended in a tap")
+	}
+}
+
+func TestPinch(t *testing.T) {
+	r := newGestureRig()
+	r.step(touch(0, PointerDown, 100, 100), touch(1, PointerDown, 120, 100))
+	r.step(touch(0, PointerMove, 90, 100), touch(1, PointerMove, 130, 100))
+	center, scale, ok := r.gestures.Pinch()
+	if !ok {
+		t.Fatal("no pinch")
+	}
+	if scale != 2 {
+		t.Errorf("scale = %v, want 2", scale)
+	}
+	if want := image.Pt(110, 100); center != want {
+		t.Errorf("center = %v, want %v", center, want)
+	}
+	if d := r.gestures.Drag(); d != (image.Point{}) {
+		t.Errorf("drag = %v, want none while pinching in place", d)
+	}
+
+	// Moving both fingers the same way pans without zooming
+	r.step(touch(0, PointerMove, 95, 110), touch(1, PointerMove, 135, 110))
+	if _, scale, _ := r.gestures.Pinch(); scale != 1 {
+		t.Errorf("scale = %v, want 1", scale)
+	}
+	if d, want := r.gestures.Drag(), image.Pt(5, 10); d != want {
+		t.Errorf("drag = %v, want %v", d, want)
+	}
+
+	r.step(touch(0, PointerUp, 95, 110))
+	r.step(touch(1, PointerUp, 135, 110))
+	if _, ok := r.gestures.Tap(); ok {
+		t.Error("lifting the fingers of a pinch was a tap")
+	}
+}
+
+func TestLongPress(t *testing.T) {
+	r := newGestureRig()
+	r.step(touch(0, PointerDown, 50, 50))
+	for range longPressTicks - 1 {
+		r.step()
+		if _, ok := r.gestures.LongPress(); ok {
+			t.Fatal("long press before the threshold")
+		}
+	}
+	r.step()
+	at, ok := r.gestures.LongPress()
+	if !ok {
+		t.Fatal("no long press at the threshold")
+	}
+	if want := image.Pt(50, 50); at != want {
+		t.Errorf("long press at %v, want %v", at, want)
+	}
+	r.step()
+	if _, ok := r.gestures.LongPress(); ok {
+		t.Error("long press reported twice")
+	}
+	r.step(touch(0, PointerUp, 50, 50))
+	if _, ok := r.gestures.Tap(); ok {
+		t.Error("a long press ended in a tap")
+	}
+}
+
+func TestLongPressCancelledByDrag(t *testing.T) {
+	r := newGestureRig()
+	r.step(mouse(PointerDown, 50, 50))
+	r.step(mouse(PointerMove, 60, 50))
+	for range longPressTicks {
+		r.step()
+		if _, ok := r.gestures.LongPress(); ok {
+			t.Fatal("a drag turned into a long press")
+		}
+	}
+}
+
+// The mouse with the buttons held in held, pressing the pointer like the
+// Ebiten source does
+type testMouse struct {
+	mouse mousePointer
+	input *InputMap
+	held  map[ebiten.MouseButton]bool
+	pos   image.Point
+}
+
+func (m *testMouse) AppendEvents(events []PointerEvent) []PointerEvent {
+	return m.mouse.appendEvents(events, m.input.Bindings(ActionSelect), func(b ebiten.MouseButton) bool { return m.held[b] }, m.pos)
+}
+
+// Click each button in turn and report which of them tapped
+func clickButtons(input *InputMap, buttons ...ebiten.MouseButton) map[ebiten.MouseButton]bool {
+	m := &testMouse{input: input, held: map[ebiten.MouseButton]bool{}, pos: image.Pt(40, 40)}
+	g := NewGestures(m, func() bool { return false })
+	tapped := map[ebiten.MouseButton]bool{}
+	for _, b := range buttons {
+		m.held[b] = true
+		g.Update()
+		m.held[b] = false
+		g.Update()
+		if at, ok := g.Tap(); ok && at == m.pos {
+			tapped[b] = true
+		}
+	}
+	return tapped
+}
+
+func TestMouseTapsFollowSelectBinding(t *testing.T) {
+	left, right := ebiten.MouseButtonLeft, ebiten.MouseButtonRight
+
+	input := NewInputMap(defaultBindings())
+	if got := clickButtons(input, left, right); !got[left] || got[right] {
+		t.Errorf("default bindings tapped with %v, want only the left button", got)
+	}
+
+	input.Rebind(ActionSelect, mouseBinding(right))
+	if got := clickButtons(input, left, right); got[left] || !got[right] {
+		t.Errorf("select bound to the right button tapped with %v, want only the right button", got)
+	}
+
+	input.Clear(ActionSelect, false)
+	if got := clickButtons(input, left, right); len(got) > 0 {
+		t.Errorf("select without mouse bindings tapped with %v, want no button", got)
+	}
+}
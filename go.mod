@@ -4,6 +4,7 @@ go 1.22.2
 
 require (
 	github.com/ebitenui/ebitenui v0.5.8
+	github.com/go-gl/mathgl v1.1.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/hajimehoshi/ebiten/v2 v2.6.6
 	golang.org/x/image v0.15.0
@@ -11,7 +12,6 @@ require (
 
 require (
 	github.com/ebitengine/purego v0.6.1 // indirect
-	github.com/go-gl/mathgl v1.1.0 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	golang.org/x/exp v0.0.0-20240222234643-814bf88cf225 // indirect
 	golang.org/x/exp/shiny v0.0.0-20240222234643-814bf88cf225 // indirect
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+var heroStyle = enemyStyle{color: hexToColor("3f7fd0"), radius: 5.5}
+
+func (g *Game) drawHero(screen *ebiten.Image) {
+	h := g.world.Hero()
+	if h == nil || !h.Alive() {
+		return
+	}
+	x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(h.Pos.X * tileSize), float32(h.Pos.Y * tileSize)})
+	if !g.sprites.Draw(screen, &g.camera, g.sprites.hero, h.Pos, g.world.Time()) {
+		vector.DrawFilledCircle(screen, float32(x), float32(y), heroStyle.radius*float32(g.camera.zoom), heroStyle.color, true)
+	}
+	// Always shown, the player needs to know when to back off
+	drawHealthBar(screen, float32(x), float32(y)-(heroStyle.radius+3)*float32(g.camera.zoom), heroStyle.radius*2*float32(g.camera.zoom), float32(h.HP/h.MaxHP()))
+}
+
+// Keep the hero in the middle of the view while it walks
+func (g *Game) followHero() {
+	if g.editor != nil || g.playback != nil || !g.player.follow {
+		return
+	}
+	if h := g.world.Hero(); h != nil && h.Alive() {
+		g.centerOn(mgl32.Vec2{float32(h.Pos.X), float32(h.Pos.Y)}.Mul(tileSize))
+	}
+}
+
+// The hero's level, health and abilities in the bottom left corner, hidden
+// in games without a hero
+type HeroPanel struct {
+	container *widget.Container
+	status    *widget.Text
+	abilities []*widget.Button
+}
+
+func (g *Game) newHeroPanel(res *uiResources, face font.Face) *HeroPanel {
+	smallFace, _ := loadFont(16)
+	p := &HeroPanel{}
+	p.container = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionEnd,
+				HorizontalPosition: widget.AnchorLayoutPositionStart,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(8)),
+			widget.RowLayoutOpts.Spacing(6),
+		)),
+	)
+	h := g.world.Hero()
+	if h == nil {
+		p.container.GetWidget().Visibility = widget.Visibility_Hide
+		return p
+	}
+
+	p.container.AddChild(widget.NewText(widget.TextOpts.Text(h.Type.Name, face, res.text.idleColor)))
+	p.status = widget.NewText(widget.TextOpts.Text("", smallFace, res.text.idleColor))
+	p.container.AddChild(p.status)
+	for i, a := range h.Type.Abilities {
+		if i >= abilitySlotCount {
+			break
+		}
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.Text("", smallFace, res.button.text),
+			widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+				Stretch: true,
+			})),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				g.world.Submit(sim.Command{Kind: sim.CmdUseAbility, Ability: a.ID})
+			}),
+		)
+		p.container.AddChild(b)
+		p.abilities = append(p.abilities, b)
+	}
+	return p
+}
+
+func (p *HeroPanel) Update(g *Game) {
+	h := g.world.Hero()
+	if h == nil || p.status == nil {
+		return
+	}
+
+	p.status.Label = fmt.Sprintf("Level %d\nHP %.0f / %.0f", h.Level, h.HP, h.MaxHP())
+	if need, ok := h.XPToLevel(); ok {
+		p.status.Label += fmt.Sprintf("\nXP %d / %d", h.XP, need)
+	}
+	if !h.Alive() {
+		p.status.Label += fmt.Sprintf("\nBack in %.0fs", h.RespawnIn())
+	}
+	for i, b := range p.abilities {
+		a := h.Type.Abilities[i]
+		label := fmt.Sprintf("%s [%s]", a.Name, bindingsLabel(g.input.Bindings(abilityAction(i)), false))
+		if cd := h.Cooldown(i); cd > 0 {
+			label += fmt.Sprintf(" %.0fs", cd)
+		}
+		b.Text().Label = label
+		b.GetWidget().Disabled = !h.Alive() || h.Cooldown(i) > 0 || g.playback != nil
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Countdown to the next wave and the button to call it early
+func (g *Game) newWavePanel(res *uiResources, face font.Face) *widget.Container {
+	c := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(2),
+		)),
+	)
+
+	row := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(10),
+		)),
+	)
+	c.AddChild(row)
+
+	g.countdownLbl = widget.NewText(
+		widget.TextOpts.Text("", face, res.text.idleColor),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	)
+	row.AddChild(g.countdownLbl)
+
+	g.nextWaveBtn = widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+		widget.ButtonOpts.Text("", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			g.world.Submit(sim.Command{Kind: sim.CmdCallNextWave})
+		}),
+	)
+	row.AddChild(g.nextWaveBtn)
+
+	return c
+}
+
+func (g *Game) updateWavePanel() {
+	if g.input.JustPressed(ActionNextWave) {
+		g.world.Submit(sim.Command{Kind: sim.CmdCallNextWave})
+	}
+
+	w := g.world
+	status := fmt.Sprintf("Gold %d   Lives %d   Wave %d/%d", w.Gold(), w.Lives(), w.WavesStarted(), w.WaveCount())
+	if w.Endless() {
+		status = fmt.Sprintf("Gold %d   Lives %d   Wave %d (endless)", w.Gold(), w.Lives(), w.WavesStarted())
+	}
+	switch {
+	case w.Lost():
+		status += "\nDefeat"
+	case w.Won():
+		status += "\nVictory"
+	}
+	g.headerLbl.Label = status
+	g.wavePreview.Update(w)
+
+	in, ok := w.NextWaveIn()
+	if !ok {
+		g.countdownLbl.Label = "Final wave"
+		g.nextWaveBtn.GetWidget().Disabled = true
+		g.nextWaveBtn.Text().Label = "Next Wave"
+		return
+	}
+	g.countdownLbl.Label = fmt.Sprintf("Next wave in %ds", int(math.Ceil(in)))
+	g.nextWaveBtn.GetWidget().Disabled = w.Over()
+	g.nextWaveBtn.Text().Label = fmt.Sprintf("Next Wave (+%dg)", w.EarlyCallBonus())
+}
+
+// Pause, speed and frame step buttons shown in the header
+func (g *Game) newSpeedControls(res *uiResources, face font.Face) *widget.Container {
+	c := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(5),
+		)),
+	)
+
+	g.timeLbl = widget.NewText(
+		widget.TextOpts.Text("", face, res.text.idleColor),
+		widget.TextOpts.Position(widget.TextPositionEnd, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	)
+	c.AddChild(g.timeLbl)
+
+	g.speedButtons = map[Speed]*widget.Button{}
+	for _, speed := range []Speed{SpeedPaused, Speed1x, Speed2x, Speed3x} {
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.Text(speed.Label(), face, res.button.text),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				if speed == SpeedPaused {
+					g.speed.TogglePause()
+				} else {
+					g.speed.Set(speed)
+				}
+			}),
+		)
+		g.speedButtons[speed] = b
+		c.AddChild(b)
+	}
+
+	c.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+		widget.ButtonOpts.Text("Step", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			g.speed.Step()
+		}),
+	))
+
+	return c
+}
+
+func (g *Game) updateSpeedControls() {
+	if g.input.JustPressed(ActionPause) {
+		g.speed.TogglePause()
+	}
+	if g.input.JustPressed(ActionSpeedUp) {
+		g.speed.SpeedUp()
+	}
+	if g.input.JustPressed(ActionStep) {
+		g.speed.Step()
+	}
+
+	for speed, b := range g.speedButtons {
+		label := speed.Label()
+		if speed == g.speed.Speed() {
+			label = "[" + label + "]"
+		}
+		b.Text().Label = label
+	}
+	t := int(g.world.Time())
+	g.timeLbl.Label = fmt.Sprintf("%02d:%02d", t/60, t%60)
+}
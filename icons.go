@@ -0,0 +1,160 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"icosahedron.com/tower-defense/sim"
+)
+
+const (
+	enemyIconSize = 28
+	towerIconSize = 20
+	traitIconSize = 14
+	starIconSize  = 20
+)
+
+var iconCache = map[string]*ebiten.Image{}
+
+// Enemy drawn the same way as on the map, scaled to fit an icon
+func enemyIcon(id string) *ebiten.Image {
+	key := "enemy:" + id
+	if i, ok := iconCache[key]; ok {
+		return i
+	}
+	style := getEnemyStyle(id)
+	i := ebiten.NewImage(enemyIconSize, enemyIconSize)
+	r := float32(enemyIconSize)/2 - 1
+	vector.DrawFilledCircle(i, enemyIconSize/2, enemyIconSize/2, r*min(1, style.radius/7+0.3), style.color, true)
+	iconCache[key] = i
+	return i
+}
+
+// Tower as a block of its color on a dark tile, for the build bar and the minimap
+func towerIcon(id string) *ebiten.Image {
+	key := "tower:" + id
+	if i, ok := iconCache[key]; ok {
+		return i
+	}
+	i := ebiten.NewImage(towerIconSize, towerIconSize)
+	vector.DrawFilledRect(i, 0, 0, towerIconSize, towerIconSize, hexToColor(backgroundColor), false)
+	vector.DrawFilledRect(i, 3, 3, towerIconSize-6, towerIconSize-6, getTowerColor(id), false)
+	iconCache[key] = i
+	return i
+}
+
+var traitColors = map[sim.Trait]color.Color{
+	sim.TraitFlying:  hexToColor("6fa8dc"),
+	sim.TraitArmored: hexToColor("a7b1b7"),
+	sim.TraitBoss:    hexToColor("e7c34b"),
+}
+
+var traitDescriptions = map[sim.Trait]string{
+	sim.TraitFlying:  "Flying: ignores the road",
+	sim.TraitArmored: "Armored: reduces damage from every hit",
+	sim.TraitBoss:    "Boss: very tough, costs many lives",
+}
+
+func traitIcon(t sim.Trait) *ebiten.Image {
+	key := "trait:" + string(t)
+	if i, ok := iconCache[key]; ok {
+		return i
+	}
+	i := ebiten.NewImage(traitIconSize, traitIconSize)
+	c := traitColors[t]
+	const s = traitIconSize
+	var p vector.Path
+	switch t {
+	case sim.TraitFlying:
+		// A pair of wings
+		p.MoveTo(0, 3)
+		p.LineTo(s/2, s-3)
+		p.LineTo(s, 3)
+		p.LineTo(s/2, s/2)
+		p.Close()
+	case sim.TraitArmored:
+		// A shield
+		p.MoveTo(1, 1)
+		p.LineTo(s-1, 1)
+		p.LineTo(s-1, s/2)
+		p.LineTo(s/2, s-1)
+		p.LineTo(1, s/2)
+		p.Close()
+	case sim.TraitBoss:
+		// A crown
+		p.MoveTo(1, s-2)
+		p.LineTo(1, 3)
+		p.LineTo(s/4+1, s/2)
+		p.LineTo(s/2, 2)
+		p.LineTo(s*3/4-1, s/2)
+		p.LineTo(s-1, 3)
+		p.LineTo(s-1, s-2)
+		p.Close()
+	}
+	fillPath(i, &p, c)
+	iconCache[key] = i
+	return i
+}
+
+var (
+	starColor      = hexToColor("e7c34b")
+	emptyStarColor = hexToColor("4b4f54")
+)
+
+// Five pointed star for level ratings, gold when earned and grey when not
+func starIcon(earned bool) *ebiten.Image {
+	key := "star:empty"
+	c := emptyStarColor
+	if earned {
+		key = "star:earned"
+		c = starColor
+	}
+	if i, ok := iconCache[key]; ok {
+		return i
+	}
+	i := ebiten.NewImage(starIconSize, starIconSize)
+	const s = starIconSize
+	var p vector.Path
+	// Alternate between the outer points and the inner corners, starting at the top
+	for n := range 10 {
+		r := float64(s)/2 - 1
+		if n%2 == 1 {
+			r *= 0.45
+		}
+		a := float64(n)*math.Pi/5 - math.Pi/2
+		x, y := float32(s/2+r*math.Cos(a)), float32(s/2+r*math.Sin(a))
+		if n == 0 {
+			p.MoveTo(x, y)
+		} else {
+			p.LineTo(x, y)
+		}
+	}
+	p.Close()
+	fillPath(i, &p, c)
+	iconCache[key] = i
+	return i
+}
+
+func fillPath(dst *ebiten.Image, p *vector.Path, c color.Color) {
+	vs, is := p.AppendVerticesAndIndicesForFilling(nil, nil)
+	r, g, b, a := c.RGBA()
+	for i := range vs {
+		vs[i].SrcX, vs[i].SrcY = 1, 1
+		vs[i].ColorR = float32(r) / 0xffff
+		vs[i].ColorG = float32(g) / 0xffff
+		vs[i].ColorB = float32(b) / 0xffff
+		vs[i].ColorA = float32(a) / 0xffff
+	}
+	dst.DrawTriangles(vs, is, whiteSubImage, &ebiten.DrawTrianglesOptions{AntiAlias: true})
+}
+
+var whiteImage = func() *ebiten.Image {
+	i := ebiten.NewImage(3, 3)
+	i.Fill(color.White)
+	return i
+}()
+
+// Sub image of a plain white image, as a source for drawing solid triangles
+var whiteSubImage = whiteImage.SubImage(whiteImage.Bounds().Inset(1)).(*ebiten.Image)
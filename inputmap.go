@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// How far a stick has to be pushed before an axis binding counts as pressed
+const axisDeadZone = 0.3
+
+type actionState struct {
+	pressed     bool
+	justPressed bool
+	value       float64
+	// Device of the strongest binding holding the action
+	source BindingKind
+}
+
+// Resolves bindings into action states once per frame
+type InputMap struct {
+	bindings map[Action][]Binding
+	states   map[Action]actionState
+	gamepads []ebiten.GamepadID
+}
+
+func NewInputMap(bindings map[Action][]Binding) *InputMap {
+	return &InputMap{
+		bindings: bindings,
+		states:   map[Action]actionState{},
+	}
+}
+
+func (m *InputMap) Update() {
+	m.gamepads = ebiten.AppendGamepadIDs(m.gamepads[:0])
+	for _, a := range allActions() {
+		prev := m.states[a]
+		var value float64
+		var source BindingKind
+		for _, b := range m.bindings[a] {
+			if v := m.bindingValue(b); v > value {
+				value = v
+				source = b.Kind
+			}
+		}
+		pressed := value > 0
+		m.states[a] = actionState{
+			pressed:     pressed,
+			justPressed: pressed && !prev.pressed,
+			value:       value,
+			source:      source,
+		}
+	}
+}
+
+// Returns how strongly a binding is activated, 0 for released and 1 for fully pressed
+func (m *InputMap) bindingValue(b Binding) float64 {
+	switch b.Kind {
+	case BindingKey:
+		if ebiten.IsKeyPressed(b.Key) {
+			return 1
+		}
+	case BindingMouse:
+		if ebiten.IsMouseButtonPressed(b.Mouse) {
+			return 1
+		}
+	case BindingGamepadButton:
+		for _, id := range m.gamepads {
+			if ebiten.IsStandardGamepadButtonPressed(id, b.Button) {
+				return 1
+			}
+		}
+	case BindingGamepadAxis:
+		var value float64
+		for _, id := range m.gamepads {
+			v := ebiten.StandardGamepadAxisValue(id, b.Axis) * float64(b.Sign)
+			if v > axisDeadZone {
+				// Rescale so the value starts at 0 on the edge of the dead zone
+				value = max(value, math.Min(1, (v-axisDeadZone)/(1-axisDeadZone)))
+			}
+		}
+		return value
+	}
+	return 0
+}
+
+func (m *InputMap) Pressed(a Action) bool {
+	return m.states[a].pressed
+}
+
+func (m *InputMap) JustPressed(a Action) bool {
+	return m.states[a].justPressed
+}
+
+// Analog strength of the action in the range [0, 1]
+func (m *InputMap) Value(a Action) float64 {
+	return m.states[a].value
+}
+
+// Device currently holding the action, empty if it isn't pressed
+func (m *InputMap) Source(a Action) BindingKind {
+	return m.states[a].source
+}
+
+// Reports whether the action is currently held by a gamepad binding
+func (m *InputMap) FromGamepad(a Action) bool {
+	s := m.states[a].source
+	return s == BindingGamepadButton || s == BindingGamepadAxis
+}
+
+func (m *InputMap) Bindings(a Action) []Binding {
+	return m.bindings[a]
+}
+
+// Returns the other actions that already use the binding
+func (m *InputMap) Conflicts(a Action, b Binding) []Action {
+	var conflicts []Action
+	for _, other := range allActions() {
+		if other == a {
+			continue
+		}
+		for _, ob := range m.bindings[other] {
+			if ob == b {
+				conflicts = append(conflicts, other)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// Replaces the action's bindings from the same device class (gamepad or keyboard/mouse) with b.
+// The binding is removed from any conflicting action, and those actions are returned.
+func (m *InputMap) Rebind(a Action, b Binding) []Action {
+	conflicts := m.Conflicts(a, b)
+	for _, other := range conflicts {
+		m.bindings[other] = removeBinding(m.bindings[other], func(ob Binding) bool {
+			return ob == b
+		})
+	}
+	m.bindings[a] = append(removeBinding(m.bindings[a], func(ob Binding) bool {
+		return ob.IsGamepad() == b.IsGamepad()
+	}), b)
+	return conflicts
+}
+
+// Removes all of the action's bindings from one device class
+func (m *InputMap) Clear(a Action, gamepad bool) {
+	m.bindings[a] = removeBinding(m.bindings[a], func(b Binding) bool {
+		return b.IsGamepad() == gamepad
+	})
+}
+
+func removeBinding(bindings []Binding, remove func(Binding) bool) []Binding {
+	var kept []Binding
+	for _, b := range bindings {
+		if !remove(b) {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+// On disk list of the levels played in order
+type campaignFile struct {
+	Levels []string `json:"levels"`
+}
+
+func loadLevel(id string) (*sim.Level, error) {
+	f, err := embeddedAssets.Open("assets/maps/" + id + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sim.LoadLevel(f)
+}
+
+// Levels of the campaign in the order they unlock
+func loadCampaign() ([]*sim.Level, error) {
+	data, err := embeddedAssets.ReadFile("assets/campaign.json")
+	if err != nil {
+		return nil, err
+	}
+	var f campaignFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Levels) == 0 {
+		return nil, errors.New("campaign has no levels")
+	}
+	var levels []*sim.Level
+	for _, id := range f.Levels {
+		l, err := loadLevel(id)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
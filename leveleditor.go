@@ -0,0 +1,631 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ebitenui/ebitenui"
+	eimage "github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/editor"
+	"icosahedron.com/tower-defense/mapgen"
+	"icosahedron.com/tower-defense/sim"
+)
+
+const (
+	// Folder in the config directory maps edited from the main menu are saved to
+	editorMapDirName = "maps"
+	// Size of maps started from scratch
+	newMapSize = 16
+	// Palette buttons show tiles this many times their size
+	paletteScale = 2
+	// Clicks this close to a waypoint, in tiles, pick it up
+	waypointGrab = 0.4
+	// Highest weight the weight tool sets a branch to
+	maxBranchWeight = 9
+)
+
+var (
+	editorPathColor     = hexToColor("e7c34b")
+	editorSpawnColor    = hexToColor("5ad16a")
+	editorExitColor     = hexToColor("e05040")
+	editorBlockedColor  = color.NRGBA{0xe0, 0x50, 0x40, 0x60}
+	editorBuildColor    = color.NRGBA{0x5a, 0xd1, 0x6a, 0x40}
+	editorHoverColor    = color.White
+	editorWaypointColor = hexToColor("dff4ff")
+)
+
+// Enum of what clicking on the map does in the editor
+type EditorTool string
+
+const (
+	ToolTiles     EditorTool = "tiles"
+	ToolRoad      EditorTool = "road"
+	ToolPath      EditorTool = "path"
+	ToolSpawn     EditorTool = "spawn"
+	ToolExit      EditorTool = "exit"
+	ToolBuildable EditorTool = "buildable"
+	ToolWeight    EditorTool = "weight"
+)
+
+// Tools in the order of their buttons, the first ones picked with the build
+// slot keys too
+var editorTools = []EditorTool{ToolTiles, ToolRoad, ToolPath, ToolSpawn, ToolExit, ToolBuildable, ToolWeight}
+
+var editorToolLabels = map[EditorTool]string{
+	ToolTiles:     "Tiles",
+	ToolRoad:      "Road",
+	ToolPath:      "Path",
+	ToolSpawn:     "Spawn",
+	ToolExit:      "Exit",
+	ToolBuildable: "Buildable",
+	ToolWeight:    "Fork Weight",
+}
+
+// Editor scene for making and changing maps. While it is open the game
+// underneath is frozen and the editor's own ui replaces the hud.
+type LevelEditor struct {
+	edit *editor.Editor
+	// File the map is saved to
+	path  string
+	tool  EditorTool
+	layer int
+	// Tile id the tiles tool paints
+	tile int
+	// Waypoint being dragged by the path, spawn or exit tool, a branch of -1 for none
+	dragging editor.Waypoint
+	// A stroke is going on while the select or cancel action is held on the map
+	stroking bool
+	// Outcome of the last save, shown until the next edit
+	message string
+	// Labels drawn on the map, like the weights at forks
+	face font.Face
+
+	status      *widget.Text
+	tools       *widget.RadioGroup
+	toolButtons []*widget.Button
+	undo        *widget.Button
+	redo        *widget.Button
+}
+
+// Path maps of the campaign are saved to when edited from the main menu
+func editorMapPath(id string) (string, error) {
+	return configPath(filepath.Join(editorMapDirName, id+".json"))
+}
+
+// Open the editor on the map at path. If there is no file there yet it
+// starts from base, or from a blank map when base is nil.
+func (g *Game) openEditor(path string, base *sim.Level) error {
+	tiles, err := mapgen.TilesFromNames(g.tileset.names)
+	if err != nil {
+		// Only the brushes missing tiles are affected
+		log.Println("Tileset is incomplete for the editor:", err)
+	}
+	l := base
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		l, err = sim.LoadLevel(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	case l == nil:
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		l = editor.NewLevel(id, newMapSize, newMapSize, tiles.Grass)
+	}
+
+	for len(g.windowClosers) > 0 {
+		g.closeWindow()
+	}
+	face, _ := loadFont(16)
+	g.editor = &LevelEditor{
+		edit:     editor.New(l, tiles.Road),
+		face:     face,
+		path:     path,
+		tool:     ToolTiles,
+		layer:    min(1, len(l.Layers)-1),
+		tile:     tiles.Grass,
+		dragging: editor.Waypoint{Branch: -1},
+	}
+	g.tooltip.Hide()
+	g.hoverTip.Hide()
+	g.cursor.visible = false
+	g.player = NewPlayer()
+	g.camera = NewCamera()
+	g.ui = g.newEditorUI()
+	return nil
+}
+
+// Leave the editor for the game it was opened over
+func (g *Game) closeEditor() {
+	for len(g.windowClosers) > 0 {
+		g.closeWindow()
+	}
+	g.editor = nil
+	g.player = NewPlayer()
+	g.camera = NewCamera()
+	g.ui = g.getEbitenUI()
+}
+
+// The level shown on the map, the one being edited while the editor is open
+func (g *Game) shownLevel() *sim.Level {
+	if g.editor != nil {
+		return g.editor.edit.Level()
+	}
+	return g.world.Level()
+}
+
+func (e *LevelEditor) save() error {
+	// Maps the game couldn't load again aren't written
+	if err := e.edit.Level().Check(); err != nil {
+		e.message = fmt.Sprintf("Can't save: %v", err)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(e.path)
+	if err != nil {
+		return err
+	}
+	if err := sim.SaveLevel(f, e.edit.Level()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	e.edit.MarkSaved()
+	e.message = "Saved to " + e.path
+	return nil
+}
+
+func (g *Game) newEditorUI() *ebitenui.UI {
+	res, _ := newUIResources()
+	face, _ := loadFont(18)
+	e := g.editor
+
+	root := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout(widget.AnchorLayoutOpts.Padding(widget.NewInsetsSimple(5)))),
+	)
+
+	header := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(eimage.NewNineSliceColor(color.Black)),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionStart,
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+				StretchHorizontal:  true,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Stretch([]bool{true, false}, []bool{true}),
+			widget.GridLayoutOpts.Spacing(20, 0),
+			widget.GridLayoutOpts.Padding(widget.NewInsetsSimple(5)),
+		)),
+	)
+	root.AddChild(header)
+	e.status = widget.NewText(
+		widget.TextOpts.Text("", face, color.White),
+		widget.TextOpts.Position(widget.TextPositionStart, widget.TextPositionCenter),
+	)
+	header.AddChild(e.status)
+
+	actions := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(widget.RowLayoutOpts.Spacing(6))),
+	)
+	header.AddChild(actions)
+	button := func(label string, clicked func()) *widget.Button {
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 10, Right: 10, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.Text(label, face, res.button.text),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				clicked()
+			}),
+		)
+		actions.AddChild(b)
+		return b
+	}
+	e.undo = button("Undo", func() { e.edit.Undo() })
+	e.redo = button("Redo", func() { e.edit.Redo() })
+	button("Waves", func() { openEditorWaves(g, 0) })
+	button("Save", func() {
+		if err := e.save(); err != nil {
+			log.Println("Failed to save map:", err)
+			e.message = "Failed to save"
+		}
+	})
+	button("Exit", g.closeEditor)
+
+	root.AddChild(g.newEditorTools(res, face))
+	root.AddChild(g.newEditorPalette(res))
+	return &ebitenui.UI{Container: root}
+}
+
+// Tool and layer buttons along the left edge
+func (g *Game) newEditorTools(res *uiResources, face font.Face) *widget.Container {
+	e := g.editor
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+				HorizontalPosition: widget.AnchorLayoutPositionStart,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(6)),
+			widget.RowLayoutOpts.Spacing(6),
+		)),
+	)
+	toggle := func(label string) *widget.Button {
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.Text(label, face, res.button.text),
+			widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{Stretch: true})),
+		)
+		c.AddChild(b)
+		return b
+	}
+
+	var tools []widget.RadioGroupElement
+	e.toolButtons = nil
+	for _, t := range editorTools {
+		b := toggle(editorToolLabels[t])
+		e.toolButtons = append(e.toolButtons, b)
+		tools = append(tools, b)
+	}
+	e.tools = widget.NewRadioGroup(
+		widget.RadioGroupOpts.Elements(tools...),
+		widget.RadioGroupOpts.ChangedHandler(func(args *widget.RadioGroupChangedEventArgs) {
+			for i, b := range e.toolButtons {
+				if b == args.Active {
+					e.tool = editorTools[i]
+				}
+			}
+		}),
+	)
+
+	c.AddChild(widget.NewText(widget.TextOpts.Text("Layer", face, res.text.disabledColor)))
+	var layers []widget.RadioGroupElement
+	var initial widget.RadioGroupElement
+	for i := range e.edit.Level().Layers {
+		b := toggle(fmt.Sprint(i + 1))
+		if i == e.layer {
+			initial = b
+		}
+		layers = append(layers, b)
+	}
+	widget.NewRadioGroup(
+		widget.RadioGroupOpts.Elements(layers...),
+		widget.RadioGroupOpts.InitialElement(initial),
+		widget.RadioGroupOpts.ChangedHandler(func(args *widget.RadioGroupChangedEventArgs) {
+			for i, b := range layers {
+				if b == args.Active {
+					e.layer = i
+				}
+			}
+		}),
+	)
+	return c
+}
+
+// Every tile of the tileset along the right edge, picking the one the tiles tool paints
+func (g *Game) newEditorPalette(res *uiResources) *widget.Container {
+	e := g.editor
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+				HorizontalPosition: widget.AnchorLayoutPositionEnd,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(6),
+			widget.GridLayoutOpts.Padding(widget.NewInsetsSimple(6)),
+			widget.GridLayoutOpts.Spacing(2, 2),
+		)),
+	)
+	var buttons []widget.RadioGroupElement
+	var ids []int
+	var initial widget.RadioGroupElement
+	for id := 1; id < g.tileset.Len(); id++ {
+		tile := g.tileset.Tile(id)
+		if tile == nil {
+			continue
+		}
+		size := tile.Bounds().Size().Mul(paletteScale)
+		img := ebiten.NewImage(size.X, size.Y)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(paletteScale, paletteScale)
+		img.DrawImage(tile, op)
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.Graphic(img),
+			widget.ButtonOpts.GraphicPadding(widget.NewInsetsSimple(3)),
+		)
+		if id == e.tile {
+			initial = b
+		}
+		c.AddChild(b)
+		buttons = append(buttons, b)
+		ids = append(ids, id)
+	}
+	widget.NewRadioGroup(
+		widget.RadioGroupOpts.Elements(buttons...),
+		widget.RadioGroupOpts.InitialElement(initial),
+		widget.RadioGroupOpts.ChangedHandler(func(args *widget.RadioGroupChangedEventArgs) {
+			for i, b := range buttons {
+				if b == args.Active {
+					e.tile = ids[i]
+				}
+			}
+			// Picking a tile means painting with it
+			e.tools.SetActive(e.toolButtons[0])
+		}),
+	)
+	return c
+}
+
+func (g *Game) updateEditor() {
+	e := g.editor
+	if g.window != None {
+		g.updateWindowNavigation()
+		if g.input.JustPressed(ActionOpenMenu) {
+			g.closeWindow()
+		}
+		return
+	}
+
+	for i, t := range editorTools {
+		if g.input.JustPressed(buildSlotAction(i)) {
+			e.tools.SetActive(e.toolButtons[i])
+			e.tool = t
+		}
+	}
+	if g.input.JustPressed(ActionUndo) && e.edit.Undo() {
+		e.message = ""
+	}
+	if g.input.JustPressed(ActionRedo) && e.edit.Redo() {
+		e.message = ""
+	}
+	if _, wy := ebiten.Wheel(); wy != 0 && !input.UIHovered {
+		g.zoomAt(image.Pt(ebiten.CursorPosition()), math.Pow(1.1, wy))
+	}
+	g.updateCursor()
+
+	paint, erase := g.input.Pressed(ActionSelect), g.input.Pressed(ActionCancel)
+	if !paint && !erase {
+		if e.stroking {
+			e.edit.End()
+		}
+		e.stroking = false
+		e.dragging = editor.Waypoint{Branch: -1}
+	} else if !e.stroking && (g.input.JustPressed(ActionSelect) || g.input.JustPressed(ActionCancel)) {
+		// Strokes only start on the map, not on the ui around it
+		if _, ok := g.targetTile(); ok {
+			e.stroking = true
+			e.message = ""
+			e.edit.Begin()
+			e.startStroke(g, paint)
+		}
+	}
+	if e.stroking {
+		e.continueStroke(g, paint)
+	}
+
+	e.undo.GetWidget().Disabled = !e.edit.CanUndo()
+	e.redo.GetWidget().Disabled = !e.edit.CanRedo()
+	l := e.edit.Level()
+	status := fmt.Sprintf("%s   %s", l.Name, editorToolLabels[e.tool])
+	if e.tool == ToolTiles {
+		status += fmt.Sprintf(" %s", strings.TrimPrefix(g.tileset.Name(e.tile), "tiles/"))
+	}
+	if tile, ok := g.targetTile(); ok {
+		status += fmt.Sprintf("   %d, %d", tile.X, tile.Y)
+	}
+	if e.edit.Changed() {
+		status += "   (unsaved)"
+	}
+	if e.message != "" {
+		status += "   " + e.message
+	}
+	e.status.Label = status
+}
+
+// Point on the map the pointer or gamepad cursor is at, in tiles and snapped
+// to half tiles like the paths of the campaign maps
+func (g *Game) editorPoint() sim.Vec2 {
+	var p mgl32.Vec2
+	if g.cursor.visible {
+		t := g.cursor.Tile()
+		p = mgl32.Vec2{float32(t.X) + 0.5, float32(t.Y) + 0.5}
+	} else {
+		x, y := ebiten.CursorPosition()
+		p = g.camera.ScreenToWorld(x, y).Mul(1.0 / tileSize)
+	}
+	return sim.Vec2{X: math.Round(float64(p[0])*2) / 2, Y: math.Round(float64(p[1])*2) / 2}
+}
+
+// Handle the press that starts a stroke, paint is false when erasing
+func (e *LevelEditor) startStroke(g *Game, paint bool) {
+	p := g.editorPoint()
+	switch e.tool {
+	case ToolPath:
+		w, ok := e.edit.WaypointAt(p, waypointGrab)
+		switch {
+		case paint && ok:
+			e.dragging = w
+		case paint:
+			e.dragging = e.edit.InsertWaypoint(p)
+		case ok:
+			e.edit.RemoveWaypoint(w)
+		}
+	case ToolSpawn:
+		i, ok := e.edit.SpawnAt(p, waypointGrab)
+		switch {
+		case paint && ok:
+			e.dragging = e.edit.SpawnWaypoint(i)
+		case paint:
+			e.dragging = e.edit.AddSpawn(p)
+		case ok:
+			e.edit.RemoveSpawn(i)
+		}
+	case ToolExit:
+		i, ok := e.edit.ExitAt(p, waypointGrab)
+		switch {
+		case paint && ok:
+			e.dragging = e.edit.ExitWaypoint(i)
+		case paint:
+			e.dragging = e.edit.AddExit(p)
+		case ok:
+			e.edit.RemoveExit(i)
+		}
+	case ToolWeight:
+		// Painting makes a branch likelier at its fork, erasing less likely
+		b, ok := e.edit.BranchAt(p, waypointGrab)
+		if !ok {
+			return
+		}
+		weight := max(1, e.edit.Level().Branches[b].Weight)
+		if paint {
+			weight = min(maxBranchWeight, weight+1)
+		} else {
+			weight = max(1, weight-1)
+		}
+		e.edit.SetWeight(b, weight)
+	}
+}
+
+// Apply the tool where the pointer is for every frame of a stroke
+func (e *LevelEditor) continueStroke(g *Game, paint bool) {
+	switch e.tool {
+	case ToolPath, ToolSpawn, ToolExit:
+		if e.dragging.Branch >= 0 {
+			e.edit.MoveWaypoint(e.dragging, g.editorPoint())
+		}
+		return
+	case ToolWeight:
+		return
+	}
+
+	tile, ok := g.targetTile()
+	if !ok {
+		return
+	}
+	c := cellOf(tile)
+	switch e.tool {
+	case ToolTiles:
+		id := e.tile
+		if !paint {
+			id = 0
+		}
+		e.edit.SetTile(e.layer, c, id)
+	case ToolRoad:
+		e.edit.SetRoad(e.layer, c, paint)
+	case ToolBuildable:
+		e.edit.SetBuildable(c, paint)
+	}
+}
+
+func (g *Game) drawEditor(screen *ebiten.Image) {
+	e := g.editor
+	l := e.edit.Level()
+	g.drawGameWorld(screen)
+
+	size := float32(tileSize * g.camera.zoom)
+	tileRect := func(i int) (float32, float32) {
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(i%l.Width) * tileSize, float32(i/l.Width) * tileSize})
+		return float32(x), float32(y)
+	}
+	// Where towers can go, in more detail while marking it
+	for i, b := range l.Buildable {
+		x, y := tileRect(i)
+		switch {
+		case !b:
+			vector.DrawFilledRect(screen, x, y, size, size, editorBlockedColor, false)
+		case e.tool == ToolBuildable:
+			vector.DrawFilledRect(screen, x, y, size, size, editorBuildColor, false)
+		}
+	}
+
+	toScreen := func(p sim.Vec2) (float32, float32) {
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(p.X * tileSize), float32(p.Y * tileSize)})
+		return float32(x), float32(y)
+	}
+	spawns, exits := l.Spawns(), l.Exits()
+	for _, b := range l.Branches {
+		for i := 1; i < len(b.Path); i++ {
+			x0, y0 := toScreen(b.Path[i-1])
+			x1, y1 := toScreen(b.Path[i])
+			vector.StrokeLine(screen, x0, y0, x1, y1, 3, editorPathColor, true)
+		}
+	}
+	for _, b := range l.Branches {
+		for _, p := range b.Path {
+			x, y := toScreen(p)
+			c, r := editorWaypointColor, size/6
+			switch {
+			case slices.Contains(spawns, p):
+				c, r = editorSpawnColor, size/3
+			case slices.Contains(exits, p):
+				c, r = editorExitColor, size/3
+			}
+			vector.DrawFilledCircle(screen, x, y, r, c, true)
+		}
+	}
+	// Numbers wave groups pick spawns by
+	if len(spawns) > 1 {
+		for i, p := range spawns {
+			x, y := toScreen(p)
+			text.Draw(screen, strconv.Itoa(i+1), e.face, int(x+size/3)+2, int(y), editorSpawnColor)
+		}
+	}
+	// Weights of the branches leaving forks, halfway along their first segment
+	for _, b := range l.Branches {
+		forks := 0
+		for _, o := range l.Branches {
+			if o.Path[0] == b.Path[0] {
+				forks++
+			}
+		}
+		if forks < 2 {
+			continue
+		}
+		x, y := toScreen(b.Path[0].Lerp(b.Path[1], 0.5))
+		text.Draw(screen, strconv.Itoa(int(max(1, b.Weight))), e.face, int(x)+4, int(y)-4, editorWaypointColor)
+	}
+
+	if !g.cursor.visible && g.window == None {
+		if tile, ok := g.targetTile(); ok {
+			x, y := tileRect(tile.Y*l.Width + tile.X)
+			vector.StrokeRect(screen, x, y, size, size, 1, editorHoverColor, false)
+		}
+	}
+	g.cursor.Draw(screen, &g.camera)
+	g.ui.Draw(screen)
+}
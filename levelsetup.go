@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/ebitenui/ebitenui/widget"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Names of the difficulty and modifiers a level is played with
+func modifierNames(content *sim.Content, ids []string) string {
+	var names []string
+	for _, id := range ids {
+		if m, ok := content.Modifiers[id]; ok {
+			names = append(names, m.Name)
+		}
+	}
+	if len(names) == 0 {
+		return content.Modifiers[sim.DefaultDifficulty].Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// What the picked difficulty and modifiers make of level i
+func setupSummary(g *Game, i int) string {
+	level, content, err := sim.ApplyModifiers(g.campaign[i], sim.DefaultContent(), g.settings.levelModifiers())
+	if err != nil {
+		return err.Error()
+	}
+	score := sim.NewWorld(level, content).ScoreBreakdown()
+	return fmt.Sprintf("Start with %d gold and %d lives   Score x%.1f", level.StartingGold, level.StartingLives, score.Multiplier)
+}
+
+// Pick the difficulty and modifiers before starting campaign level i
+func openLevelSetup(g *Game, i int, endless bool) {
+	res, _ := newUIResources()
+	face, _ := loadFont(20)
+	smallFace, _ := loadFont(16)
+	content := sim.DefaultContent()
+	s := g.settings
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(res.panel.padding),
+			widget.RowLayoutOpts.Spacing(10),
+		)),
+	)
+
+	summary := widget.NewText(widget.TextOpts.Text(setupSummary(g, i), smallFace, res.text.idleColor))
+	description := widget.NewText(widget.TextOpts.Text("", smallFace, res.text.idleColor))
+
+	// One toggle button per difficulty, only one can be picked
+	dc := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(10),
+		)),
+	)
+	var buttons []widget.RadioGroupElement
+	var initial widget.RadioGroupElement
+	for _, id := range content.Difficulties {
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(res.button.padding),
+			widget.ButtonOpts.Text(content.Modifiers[id].Name, face, res.button.text),
+		)
+		if id == s.difficulty || initial == nil && id == sim.DefaultDifficulty {
+			initial = b
+		}
+		dc.AddChild(b)
+		buttons = append(buttons, b)
+	}
+	c.AddChild(dc)
+	widget.NewRadioGroup(
+		widget.RadioGroupOpts.Elements(buttons...),
+		widget.RadioGroupOpts.InitialElement(initial),
+		widget.RadioGroupOpts.ChangedHandler(func(args *widget.RadioGroupChangedEventArgs) {
+			s.difficulty = content.Difficulties[slices.Index(buttons, args.Active)]
+			description.Label = content.Modifiers[s.difficulty].Description
+			summary.Label = setupSummary(g, i)
+		}),
+	)
+	if m, ok := content.Modifiers[s.difficulty]; ok {
+		description.Label = m.Description
+	}
+	c.AddChild(description)
+
+	for _, id := range content.ModifierOrder {
+		m := content.Modifiers[id]
+		c.AddChild(widget.NewLabeledCheckbox(
+			widget.LabeledCheckboxOpts.Spacing(res.checkbox.spacing),
+			widget.LabeledCheckboxOpts.CheckboxOpts(
+				widget.CheckboxOpts.InitialState(boolToCheck(slices.Contains(s.modifiers, id))),
+				widget.CheckboxOpts.ButtonOpts(widget.ButtonOpts.Image(res.checkbox.image)),
+				widget.CheckboxOpts.Image(res.checkbox.graphic),
+				widget.CheckboxOpts.StateChangedHandler(func(args *widget.CheckboxChangedEventArgs) {
+					// Kept in the listed order so the same picks always apply the same way
+					var picked []string
+					for _, other := range content.ModifierOrder {
+						on := slices.Contains(s.modifiers, other)
+						if other == id {
+							on = args.State == widget.WidgetChecked
+						}
+						if on {
+							picked = append(picked, other)
+						}
+					}
+					s.modifiers = picked
+					summary.Label = setupSummary(g, i)
+				})),
+			widget.LabeledCheckboxOpts.LabelOpts(widget.LabelOpts.Text(fmt.Sprintf("%s: %s", m.Name, m.Description), smallFace, res.label.text))))
+	}
+	c.AddChild(summary)
+
+	c.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Start", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			g.saveSettings()
+			if endless {
+				g.startEndless(i)
+			} else {
+				g.startLevel(i)
+			}
+		}),
+	))
+
+	title := g.campaign[i].Name
+	if endless {
+		title += " (endless)"
+	}
+	window := widget.NewWindow(
+		widget.WindowOpts.Modal(),
+		widget.WindowOpts.Contents(c),
+		widget.WindowOpts.TitleBar(newWindowTitleBar(g, res, title, face), 30),
+		widget.WindowOpts.Draggable(),
+	)
+	windowSize := input.GetWindowSize()
+	r := image.Rect(0, 0, 620, 200+32*len(content.ModifierOrder))
+	r = r.Add(image.Point{(windowSize.X - r.Dx()) / 2, (windowSize.Y - r.Dy()) / 2})
+	window.SetLocation(r)
+
+	previous := g.window
+	g.window = LevelSetup
+	rw := g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		g.window = previous
+		rw()
+	})
+}
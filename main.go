@@ -16,12 +16,14 @@
 package main
 
 import (
-	"bytes"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	_ "image/png"
 	"log"
+	"math"
+	"strings"
 
 	"github.com/ebitenui/ebitenui"
 	eimage "github.com/ebitenui/ebitenui/image"
@@ -32,31 +34,67 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/examples/resources/images"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
+	"icosahedron.com/tower-defense/sim"
 )
 
 const (
 	screenWidth  = 920
 	screenHeight = 920
 	tileSize     = 16
-	tileMapWidth = 15
 	title        = "Icosahedron Games: Tower Defense"
 )
 
 func main() {
+	replayPath := flag.String("replay", "", "watch a replay file instead of playing")
+	editPath := flag.String("edit", "", "open a map file in the level editor, a new map is made if it doesn't exist")
+	flag.Parse()
+
+	settings, err := loadSettings()
+	if err != nil {
+		log.Println("Failed to load settings:", err)
+	}
+	profile, err := loadProfile()
+	if err != nil {
+		log.Println("Failed to load profile:", err)
+	}
+	campaign, err := loadCampaign()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Pick up the campaign where the player left it
+	start := profile.NextLevel(campaign)
 	g := &Game{
-		layers:     getLayers(),
-		tilesImage: getTileImage(),
-		settings: &Settings{
-			showFPS: false,
-			vSynch:  ebiten.IsVsyncEnabled(),
-		},
-		player: NewPlayer(),
+		tileset:    getTileset(),
+		campaign:   campaign,
+		level:      start,
+		profile:    profile,
+		settings:   settings,
+		input:      NewInputMap(settings.bindings),
+		window:     None,
+		player:     NewPlayer(),
+		camera:     NewCamera(),
+		tooltip:    NewTooltip(),
+		hoverTip:   NewTooltip(),
+		combatText: NewCombatText(),
+		sprites:    NewSprites(),
+		particles:  NewParticles(),
+		speed:      NewSpeedControl(),
 	}
+	g.world = g.newWorld(start, settings.levelModifiers(), false)
+	g.pointer = NewGestures(newEbitenPointerSource(g.input), func() bool { return input.UIHovered })
 	g.ui = g.getEbitenUI()
+	if *replayPath != "" {
+		if err := g.startPlayback(*replayPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *editPath != "" {
+		if err := g.openEditor(*editPath, nil); err != nil {
+			log.Fatal(err)
+		}
+	}
 	v := mgl32.Vec2{}
 	fmt.Printf("%f\n", v[0])
 
@@ -68,49 +106,108 @@ func main() {
 	}
 }
 
+// Walks the hero around the map and uses its abilities. Without a hero to
+// steer, like in the level editor or while watching a replay, the move
+// actions move the view instead.
 type Player struct {
+	// World position of the top left corner of the view
 	position mgl32.Vec2
+	// Keep the hero in the middle of the view, until the view is moved away from it
+	follow bool
+	// Walking direction last sent to the hero of heroWorld, only changes are sent
+	heroMove  sim.Vec2
+	heroWorld *sim.World
 }
 
 func NewPlayer() Player {
 	return Player{
-		mgl32.Vec2{0, 0},
+		position: mgl32.Vec2{0, 0},
 	}
 }
 
-func (player *Player) UpdatePlayer(deltaTime float32) {
+// world is the one whose hero the player steers, nil when there is none to steer
+func (player *Player) UpdatePlayer(deltaTime float32, in *InputMap, world *sim.World) {
 	// Game units / second
 	var movementSpeed float32 = 100
 
-	var movementDir = mgl32.Vec2{0, 0}
-
-	if inpututil.KeyPressDuration(ebiten.KeyW) > 0 {
-		movementDir = movementDir.Add(mgl32.Vec2{0, -1})
-	}
-	if inpututil.KeyPressDuration(ebiten.KeyS) > 0 {
-		movementDir = movementDir.Add(mgl32.Vec2{0, 1})
+	// Analog sticks give partial values so the player can move slower than full speed
+	var movementDir = mgl32.Vec2{
+		float32(in.Value(ActionMoveRight) - in.Value(ActionMoveLeft)),
+		float32(in.Value(ActionMoveDown) - in.Value(ActionMoveUp)),
 	}
-	if inpututil.KeyPressDuration(ebiten.KeyA) > 0 {
-		movementDir = movementDir.Add(mgl32.Vec2{-1, 0})
+
+	if world != nil && world.Hero() != nil {
+		player.steerHero(movementDir, in, world)
+		return
 	}
-	if inpututil.KeyPressDuration(ebiten.KeyD) > 0 {
-		movementDir = movementDir.Add(mgl32.Vec2{1, 0})
+	if player.heroWorld != nil && player.heroMove != (sim.Vec2{}) {
+		// Steering stopped with the hero walking, like when a menu opened
+		player.heroMove = sim.Vec2{}
+		stop := sim.Vec2{}
+		player.heroWorld.Submit(sim.Command{Kind: sim.CmdMoveHero, Move: &stop})
 	}
-
 	if movementDir[0] != float32(0) || movementDir[1] != float32(0) {
-		movementDir = movementDir.Normalize()
+		if movementDir.Len() > 1 {
+			movementDir = movementDir.Normalize()
+		}
 		var frameDisplacement = movementDir.Mul(movementSpeed * deltaTime)
 		player.position = player.position.Add(frameDisplacement)
 		log.Println("Position {", player.position[0], ",", player.position[1], "}")
 	}
 }
 
+func (player *Player) steerHero(movementDir mgl32.Vec2, in *InputMap, world *sim.World) {
+	hero := world.Hero()
+	if player.heroWorld != world {
+		// A new game or one loaded from a save, the hero may be walking already
+		player.heroWorld = world
+		player.heroMove = hero.Move
+	}
+	move := heroMove(movementDir)
+	if move != player.heroMove {
+		player.heroMove = move
+		world.Submit(sim.Command{Kind: sim.CmdMoveHero, Move: &move})
+	}
+	if move != (sim.Vec2{}) {
+		player.follow = true
+	}
+	for i, a := range hero.Type.Abilities {
+		if i < abilitySlotCount && in.JustPressed(abilityAction(i)) {
+			world.Submit(sim.Command{Kind: sim.CmdUseAbility, Ability: a.ID})
+		}
+	}
+}
+
+// Round a stick direction to one of 16 headings at half or full speed, so
+// a stick held still doesn't send a command every frame
+func heroMove(dir mgl32.Vec2) sim.Vec2 {
+	l := float64(dir.Len())
+	if l < 0.2 {
+		return sim.Vec2{}
+	}
+	speed := 1.0
+	if l < 0.6 {
+		speed = 0.5
+	}
+	const step = math.Pi / 8
+	angle := math.Round(math.Atan2(float64(dir[1]), float64(dir[0]))/step) * step
+	// Rounded further so straight headings come out as whole numbers in replays
+	round := func(v float64) float64 { return math.Round(v*1e6) / 1e6 }
+	return sim.Vec2{X: round(math.Cos(angle) * speed), Y: round(math.Sin(angle) * speed)}
+}
+
 // Enum of windows that can be open
 type Window string
 
 const (
-	MainMenu Window = "mainMenu"
-	None     Window = "none"
+	MainMenu     Window = "mainMenu"
+	ControlsMenu Window = "controlsMenu"
+	LevelSelect  Window = "levelSelect"
+	Results      Window = "results"
+	Daily        Window = "daily"
+	LevelSetup   Window = "levelSetup"
+	EditorWaves  Window = "editorWaves"
+	None         Window = "none"
 )
 
 type PerFrame struct {
@@ -120,49 +217,328 @@ type PerFrame struct {
 
 // God class
 type Game struct {
-	tilesImage *ebiten.Image
-	layers     [][]int
+	tileset *Tileset
+	// Levels in the order they unlock and the index of the one being played
+	campaign []*sim.Level
+	level    int
+	profile  *Profile
+	// Set once the results of the current game were recorded
+	resultsShown bool
+	// Drives the world while watching a replay, nil when playing
+	playback  *sim.Playback
+	replayBar *ReplayBar
+	// Challenge being played, nil outside of daily challenges
+	daily *sim.Challenge
+	// Set while the level editor is open instead of the game
+	editor *LevelEditor
 
 	ui        *ebitenui.UI
 	headerLbl *widget.Text
 	settings  *Settings
+	input     *InputMap
 	window    Window
 	player    Player
+	camera    Camera
+	cursor    Cursor
+	pointer   *Gestures
+	tooltip   Tooltip
 	perFrame  PerFrame
-}
-
-type Settings struct {
-	showFPS bool
-	vSynch  bool
+	world     *sim.World
+	speed     SpeedControl
+	// Size of the screen in pixels, as given to the last Layout
+	screenSize image.Point
+
+	timeLbl      *widget.Text
+	speedButtons map[Speed]*widget.Button
+	countdownLbl *widget.Text
+	nextWaveBtn  *widget.Button
+	wavePreview  *WavePreview
+	buildButtons map[string]*widget.Button
+	towerPanel   *TowerPanel
+	minimap      *Minimap
+	heroPanel    *HeroPanel
+
+	// Tower type placed by selecting a tile, empty when not building
+	buildType string
+	// Ids of the towers showing their range, 0 for none
+	selectedTower int
+	hoveredTower  int
+	// Describes whatever is under the mouse, hidden while the long press tooltip is up
+	hoverTip Tooltip
+	// Damage and gold numbers floating over the map
+	combatText *CombatText
+	sprites    *Sprites
+	particles  *Particles
+
+	// Close functions of the open windows, the last one is on top
+	windowClosers []func()
+	// Set while a binding button in the controls window waits for input
+	rebind         *rebindCapture
+	bindingButtons []bindingButton
+	// Mouse position on the previous frame, used to pan the camera by dragging
+	lastMouse image.Point
 }
 
 func (g *Game) Update() error {
 	// Ensure that the UI is updated to receive events
 	g.ui.Update()
+	g.input.Update()
+	g.pointer.Update()
+	if g.rebind != nil {
+		g.updateRebind()
+		return nil
+	}
 	g.perFrame.deltaTime64 = 1.0 / ebiten.ActualTPS()
 	g.perFrame.deltaTime64 = max(0.001, g.perFrame.deltaTime64)
 	g.perFrame.deltaTime32 = float32(g.perFrame.deltaTime64)
-	g.player.UpdatePlayer(g.perFrame.deltaTime32)
-
-	// Update the Label text to indicate if the ui is currently being hovered over or not
-	g.headerLbl.Label = fmt.Sprintf("Game Demo!\nUI is hovered: %t", input.UIHovered)
+	var steered *sim.World
+	if g.editor == nil && g.playback == nil && g.window == None {
+		steered = g.world
+	}
+	g.player.UpdatePlayer(g.perFrame.deltaTime32, g.input, steered)
+	g.updatePan()
+	if g.editor != nil {
+		g.updateEditor()
+		g.camera.position = g.player.position
+		return nil
+	}
 
-	// Log out if we have clicked on the gamefield and NOT the ui
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !input.UIHovered {
-		log.Println("Mouse clicked on gamefield")
+	if g.window == None {
+		g.updateSpeedControls()
+		g.updateWavePanel()
+		g.updateBuildBar()
+		g.towerPanel.Update(g)
+		g.minimap.Update(g)
+		g.heroPanel.Update(g)
+		if g.playback != nil {
+			g.replayBar.Update(g)
+		}
+	}
+	for i := g.speed.TicksThisFrame(); i > 0; i-- {
+		if g.playback != nil {
+			g.playback.Step()
+		} else {
+			g.world.Step()
+		}
+		g.combatText.Step()
+		g.combatText.AddEvents(g.world.Events())
+		g.sprites.AddEvents(g.world.Events(), g.world.Time())
+		g.particles.Step()
+		g.particles.AddEvents(g.world.Events())
 	}
+	g.sprites.Update(g.world)
+	g.updateResults()
+
+	if g.window != None {
+		g.updateWindowNavigation()
+		g.hoveredTower = 0
+		g.hoverTip.Hide()
+	} else {
+		g.updatePointer()
+		g.updateCursor()
+		g.updateTileActions()
+		g.updateHover()
+	}
+	g.followHero()
+	g.camera.position = g.player.position
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		log.Println("Escape is pressed")
-		if g.window != MainMenu {
+	if g.input.JustPressed(ActionOpenMenu) {
+		log.Println("Open menu is pressed")
+		if g.window == None {
 			g.window = MainMenu
+			g.speed.PauseForMenu()
 			openMainMenu(g)
+			if g.input.FromGamepad(ActionOpenMenu) {
+				g.focusFirstWidget()
+			}
+		} else if g.window == MainMenu || g.window == LevelSelect || g.window == Results || g.window == Daily || g.window == LevelSetup {
+			g.closeWindow()
 		}
 	}
 
 	return nil
 }
 
+// Handle mouse and touch gestures on the gamefield
+func (g *Game) updatePointer() {
+	if d := g.pointer.Drag(); d != (image.Point{}) {
+		g.panBy(d)
+		g.tooltip.Hide()
+	}
+	if center, scale, ok := g.pointer.Pinch(); ok {
+		g.zoomAt(center, scale)
+	}
+	if _, wy := ebiten.Wheel(); wy != 0 && !input.UIHovered {
+		g.zoomAt(image.Pt(ebiten.CursorPosition()), math.Pow(1.1, wy))
+	}
+
+	if p, ok := g.pointer.Tap(); ok {
+		g.tooltip.Hide()
+		g.cursor.visible = false
+		if tile, ok := g.tileAt(p); ok {
+			g.selectTile(tile)
+		}
+	}
+	if p, ok := g.pointer.LongPress(); ok {
+		if e := g.enemyAt(p); e != nil {
+			g.tooltip.Show(describeEnemy(e), p)
+		} else if tile, ok := g.tileAt(p); ok {
+			if t := g.world.TowerAt(cellOf(tile)); t != nil {
+				g.tooltip.Show(describeTower(t), p)
+			} else {
+				g.tooltip.Show(g.describeTile(tile), p)
+			}
+		}
+	}
+}
+
+// Move the view so the world follows a pointer that moved by d screen pixels
+func (g *Game) panBy(d image.Point) {
+	world := mgl32.Vec2{float32(d.X), float32(d.Y)}.Mul(float32(1 / g.camera.zoom))
+	g.player.position = g.player.position.Sub(world)
+	g.player.follow = false
+}
+
+// Zoom the view by scale while keeping the world point under center in place
+func (g *Game) zoomAt(center image.Point, scale float64) {
+	before := g.camera.ScreenToWorld(center.X, center.Y)
+	g.camera.zoom = min(maxZoom, max(minZoom, g.camera.zoom*scale))
+	after := g.camera.ScreenToWorld(center.X, center.Y)
+	g.player.position = g.player.position.Add(before.Sub(after))
+	g.camera.position = g.player.position
+}
+
+// Drag the view while the pan camera action is held
+func (g *Game) updatePan() {
+	x, y := ebiten.CursorPosition()
+	if g.input.Pressed(ActionPanCamera) && !g.input.JustPressed(ActionPanCamera) {
+		g.panBy(image.Point{x, y}.Sub(g.lastMouse))
+	}
+	// Using the mouse hands tile picking back from the gamepad cursor
+	if (image.Point{x, y}) != g.lastMouse {
+		g.cursor.visible = false
+	}
+	g.lastMouse = image.Point{x, y}
+}
+
+func (g *Game) updateCursor() {
+	wasVisible := g.cursor.visible
+	if !g.cursor.Update(g.perFrame.deltaTime32, g.input, g.mapSize()) {
+		return
+	}
+	if !wasVisible {
+		// Start from the middle of the screen rather than wherever the cursor was last left
+		p := g.camera.ScreenToWorld(g.screenSize.X/2, g.screenSize.Y/2).Mul(1.0 / tileSize)
+		g.cursor.SetTile(image.Point{int(p[0]), int(p[1])})
+	}
+	g.cursor.visible = true
+}
+
+// Size of the map in tiles
+func (g *Game) mapSize() image.Point {
+	l := g.shownLevel()
+	return image.Point{l.Width, l.Height}
+}
+
+// Tile the player is pointing at with either the gamepad cursor or the mouse
+func (g *Game) targetTile() (image.Point, bool) {
+	if g.cursor.visible {
+		return g.cursor.Tile(), true
+	}
+	if input.UIHovered {
+		return image.Point{}, false
+	}
+	return g.tileAt(image.Pt(ebiten.CursorPosition()))
+}
+
+// Tile under a point on the screen
+func (g *Game) tileAt(p image.Point) (image.Point, bool) {
+	w := g.camera.ScreenToWorld(p.X, p.Y).Mul(1.0 / tileSize)
+	t := image.Point{int(math.Floor(float64(w[0]))), int(math.Floor(float64(w[1])))}
+	return t, t.In(image.Rectangle{Max: g.mapSize()})
+}
+
+func (g *Game) describeTile(t image.Point) string {
+	i := t.Y*g.world.Level().Width + t.X
+	var ids []string
+	for _, l := range g.world.Level().Layers {
+		ids = append(ids, fmt.Sprint(l[i]))
+	}
+	return fmt.Sprintf("Tile %d, %d\nLayers: %s", t.X, t.Y, strings.Join(ids, ", "))
+}
+
+// Build, select or deselect depending on what is on the tile
+func (g *Game) selectTile(tile image.Point) {
+	if t := g.world.TowerAt(cellOf(tile)); t != nil {
+		g.selectedTower = t.ID
+		g.buildType = ""
+		return
+	}
+	if g.buildType != "" {
+		g.world.Submit(sim.Command{Kind: sim.CmdBuild, Tower: g.buildType, Cell: cellOf(tile)})
+		return
+	}
+	g.selectedTower = 0
+}
+
+// Tower the upgrade and sell actions apply to, the one under the cursor before the selected one
+func (g *Game) actionTower(tile image.Point, onMap bool) *sim.Tower {
+	if onMap {
+		if t := g.world.TowerAt(cellOf(tile)); t != nil {
+			return t
+		}
+	}
+	return g.world.Tower(g.selectedTower)
+}
+
+func (g *Game) updateTileActions() {
+	if g.input.JustPressed(ActionCancel) {
+		g.buildType = ""
+		g.selectedTower = 0
+	}
+
+	tile, ok := g.targetTile()
+	// Act on the gamefield and NOT the ui.
+	// Mouse clicks select through the pointer gestures instead.
+	if ok && g.input.JustPressed(ActionSelect) && g.input.Source(ActionSelect) != BindingMouse {
+		g.tooltip.Hide()
+		g.selectTile(tile)
+	}
+	if ok && g.input.JustPressed(ActionBuild) {
+		buildType := g.buildType
+		if buildType == "" {
+			buildType = g.world.Content().TowerOrder[0]
+		}
+		g.world.Submit(sim.Command{Kind: sim.CmdBuild, Tower: buildType, Cell: cellOf(tile)})
+	}
+	if g.input.JustPressed(ActionUpgrade) {
+		if t := g.actionTower(tile, ok); t != nil {
+			g.upgradeTower(t)
+		}
+	}
+	if g.input.JustPressed(ActionSell) {
+		if t := g.actionTower(tile, ok); t != nil {
+			g.sellTower(t)
+		}
+	}
+}
+
+// Close the window on top
+func (g *Game) closeWindow() {
+	if len(g.windowClosers) == 0 {
+		return
+	}
+	closeWindow := g.windowClosers[len(g.windowClosers)-1]
+	g.windowClosers = g.windowClosers[:len(g.windowClosers)-1]
+	closeWindow()
+}
+
+func (g *Game) saveSettings() {
+	if err := g.settings.save(); err != nil {
+		log.Println("Failed to save settings:", err)
+	}
+}
+
 // Open the main menu, triggered by pressing escape key
 func openMainMenu(g *Game) {
 	res, _ := newUIResources()
@@ -191,8 +567,7 @@ func openMainMenu(g *Game) {
 		widget.ButtonOpts.TextPadding(res.button.padding),
 		widget.ButtonOpts.Text("X", face, res.button.text),
 		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
-			g.window = None
-			rw()
+			g.closeWindow()
 		}),
 		widget.ButtonOpts.TabOrder(99),
 	))
@@ -245,6 +620,18 @@ func openMainMenu(g *Game) {
 
 	c.AddChild(cb2)
 
+	// Health bars
+	c.AddChild(widget.NewLabeledCheckbox(
+		widget.LabeledCheckboxOpts.Spacing(res.checkbox.spacing),
+		widget.LabeledCheckboxOpts.CheckboxOpts(
+			widget.CheckboxOpts.InitialState(boolToCheck(g.settings.showHealthBars)),
+			widget.CheckboxOpts.ButtonOpts(widget.ButtonOpts.Image(res.checkbox.image)),
+			widget.CheckboxOpts.Image(res.checkbox.graphic),
+			widget.CheckboxOpts.StateChangedHandler(func(args *widget.CheckboxChangedEventArgs) {
+				g.settings.showHealthBars = args.State == widget.WidgetChecked
+			})),
+		widget.LabeledCheckboxOpts.LabelOpts(widget.LabelOpts.Text("Health bars", face, res.label.text))))
+
 	bc := widget.NewContainer(
 		widget.ContainerOpts.Layout(widget.NewRowLayout(
 			widget.RowLayoutOpts.Spacing(15),
@@ -252,6 +639,100 @@ func openMainMenu(g *Game) {
 	)
 	c.AddChild(bc)
 
+	bc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Controls", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			openControlsMenu(g)
+		}),
+	))
+
+	bc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Level Select", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			openLevelSelect(g)
+		}),
+	))
+
+	bc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Daily Challenge", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			openDaily(g)
+		}),
+	))
+
+	bc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Level Editor", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			// Edits of campaign maps go to the config folder, the game's own stay as they are
+			l := g.campaign[g.level]
+			path, err := editorMapPath(l.ID)
+			if err == nil {
+				err = g.openEditor(path, l)
+			}
+			if err != nil {
+				log.Println("Failed to open the level editor:", err)
+			}
+		}),
+	))
+
+	status := widget.NewText(widget.TextOpts.Text("", face, res.label.text.Idle))
+	sc := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(15),
+		)),
+	)
+	c.AddChild(sc)
+
+	sc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Save Game", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			if err := g.saveGame(); err != nil {
+				log.Println("Failed to save game:", err)
+				status.Label = "Failed to save"
+				return
+			}
+			status.Label = "Saved"
+		}),
+	))
+
+	sc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Load Game", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			// Closes this menu when it works
+			if err := g.loadGame(); err != nil {
+				log.Println("Failed to load game:", err)
+				status.Label = "Failed to load"
+			}
+		}),
+	))
+
+	sc.AddChild(widget.NewButton(
+		widget.ButtonOpts.Image(res.button.image),
+		widget.ButtonOpts.TextPadding(res.button.padding),
+		widget.ButtonOpts.Text("Save Replay", face, res.button.text),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			if _, err := g.saveReplay(); err != nil {
+				log.Println("Failed to save replay:", err)
+				status.Label = "Failed to save"
+				return
+			}
+			status.Label = "Saved"
+		}),
+	))
+	c.AddChild(status)
+
 	window = widget.NewWindow(
 		widget.WindowOpts.Modal(),
 		widget.WindowOpts.Contents(c),
@@ -259,7 +740,7 @@ func openMainMenu(g *Game) {
 		widget.WindowOpts.Draggable(),
 		widget.WindowOpts.Resizeable(),
 		widget.WindowOpts.MinSize(500, 200),
-		widget.WindowOpts.MaxSize(700, 400),
+		widget.WindowOpts.MaxSize(700, 450),
 		widget.WindowOpts.ResizeHandler(func(args *widget.WindowChangedEventArgs) {
 			fmt.Println("Resize: ", args.Rect)
 		}),
@@ -268,11 +749,17 @@ func openMainMenu(g *Game) {
 		}),
 	)
 	windowSize := input.GetWindowSize()
-	r := image.Rect(0, 0, 550, 250)
+	r := image.Rect(0, 0, 650, 420)
 	r = r.Add(image.Point{windowSize.X / 4 / 2, windowSize.Y * 2 / 3 / 2})
 	window.SetLocation(r)
 
 	rw = g.ui.AddWindow(window)
+	g.windowClosers = append(g.windowClosers, func() {
+		g.window = None
+		g.speed.ResumeFromMenu()
+		g.saveSettings()
+		rw()
+	})
 }
 
 func boolToCheck(test bool) widget.WidgetState {
@@ -283,10 +770,32 @@ func boolToCheck(test bool) widget.WidgetState {
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	if g.editor != nil {
+		g.drawEditor(screen)
+		g.drawFPS(screen)
+		return
+	}
 	// Draw the tilemap
 	g.drawGameWorld(screen)
+	g.drawRanges(screen)
+	g.drawTowers(screen)
+	g.sprites.DrawCorpses(screen, &g.camera, g.world.Time())
+	g.drawEnemies(screen)
+	g.drawHero(screen)
+	g.drawProjectiles(screen)
+	g.particles.Draw(screen, &g.camera)
+	g.combatText.Draw(screen, &g.camera)
+	g.cursor.Draw(screen, &g.camera)
+	if !g.tooltip.visible {
+		g.hoverTip.Draw(screen)
+	}
+	g.tooltip.Draw(screen)
 	// Ensure ui.Draw is called after the gameworld is drawn
 	g.ui.Draw(screen)
+	g.drawFPS(screen)
+}
+
+func (g *Game) drawFPS(screen *ebiten.Image) {
 	// Print FPS on screen
 	if g.settings.showFPS {
 		ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %f", ebiten.ActualFPS()))
@@ -295,10 +804,13 @@ func (g *Game) Draw(screen *ebiten.Image) {
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	// The window size is 0x0 in browsers and on mobile, so the camera goes by this
+	g.screenSize = image.Pt(outsideWidth, outsideHeight)
 	return outsideWidth, outsideHeight
 }
 
 func (g *Game) getEbitenUI() *ebitenui.UI {
+	res, _ := newUIResources()
 	// load label text font
 	face, _ := loadFont(18)
 
@@ -321,62 +833,37 @@ func (g *Game) getEbitenUI() *ebitenui.UI {
 			// Uncomment this to not track that you are hovering over this header
 			// widget.WidgetOpts.TrackHover(false),
 		),
-		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(3),
+			widget.GridLayoutOpts.Stretch([]bool{true, false, false}, []bool{true}),
+			widget.GridLayoutOpts.Spacing(20, 0),
+			widget.GridLayoutOpts.Padding(widget.NewInsetsSimple(5)),
+		)),
 	)
 
 	rootContainer.AddChild(headerContainer)
 
-	hProgressbar := widget.NewProgressBar(
-		widget.ProgressBarOpts.WidgetOpts(
-			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
-				VerticalPosition:   widget.AnchorLayoutPositionEnd,
-				HorizontalPosition: widget.AnchorLayoutPositionCenter,
-				StretchHorizontal:  true,
-				StretchVertical:    false,
-			}),
-			// Set the minimum size for the progress bar.
-			// This is necessary if you wish to have the progress bar be larger than
-			// the provided track image. In this exampe since we are using NineSliceColor
-			// which is 1px x 1px we must set a minimum size.
-			widget.WidgetOpts.MinSize(200, 20),
-			// Set this parameter to indicate we want do not want to track that this ui element is being hovered over.
-			// widget.WidgetOpts.TrackHover(false),
-		),
-		widget.ProgressBarOpts.Images(
-			// Set the track images (Idle, Disabled).
-			&widget.ProgressBarImage{
-				Idle: eimage.NewNineSliceColor(color.NRGBA{100, 100, 100, 255}),
-			},
-			// Set the progress images (Idle, Disabled).
-			&widget.ProgressBarImage{
-				Idle: eimage.NewNineSliceColor(color.NRGBA{255, 255, 100, 255}),
-			},
-		),
-		// Set the min, max, and current values.
-		widget.ProgressBarOpts.Values(0, 10, 7),
-		// Set how much of the track is displayed when the bar is overlayed.
-		widget.ProgressBarOpts.TrackPadding(widget.Insets{
-			Top:    2,
-			Bottom: 2,
-		}),
-	)
-
-	rootContainer.AddChild(hProgressbar)
-
-	// Create a label to show the percentage on top of the progress bar
-	label2 := widget.NewText(
-		widget.TextOpts.Text("70%", face, color.Black),
-		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
-		widget.TextOpts.WidgetOpts(
-			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
-				VerticalPosition:   widget.AnchorLayoutPositionEnd,
-				HorizontalPosition: widget.AnchorLayoutPositionCenter,
-				StretchHorizontal:  false,
-				StretchVertical:    false,
-			}),
-		),
+	g.headerLbl = widget.NewText(
+		widget.TextOpts.Text("", face, color.White),
+		widget.TextOpts.Position(widget.TextPositionStart, widget.TextPositionCenter),
 	)
-	rootContainer.AddChild(label2)
+	headerContainer.AddChild(g.headerLbl)
+	headerContainer.AddChild(g.newWavePanel(res, face))
+	headerContainer.AddChild(g.newSpeedControls(res, face))
+
+	g.wavePreview = newWavePreview(res)
+	rootContainer.AddChild(g.wavePreview.container)
+	rootContainer.AddChild(g.newBuildBar(res, face))
+	g.towerPanel = g.newTowerPanel(res, face)
+	rootContainer.AddChild(g.towerPanel.container)
+	g.minimap = g.newMinimap(res)
+	rootContainer.AddChild(g.minimap.container)
+	g.heroPanel = g.newHeroPanel(res, face)
+	rootContainer.AddChild(g.heroPanel.container)
+	if g.playback != nil {
+		g.replayBar = g.newReplayBar(res, face)
+		rootContainer.AddChild(g.replayBar.container)
+	}
 
 	return &ebitenui.UI{
 		Container: rootContainer,
@@ -384,75 +871,29 @@ func (g *Game) getEbitenUI() *ebitenui.UI {
 }
 
 func (g *Game) drawGameWorld(screen *ebiten.Image) {
-	w := g.tilesImage.Bounds().Dx()
-	tileXCount := w / tileSize
-
-	for _, l := range g.layers {
+	width := g.shownLevel().Width
+	for _, l := range g.shownLevel().Layers {
 		for i, t := range l {
+			tile := g.tileset.Tile(t)
+			if tile == nil {
+				continue
+			}
 			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(float64((i%tileMapWidth)*tileSize), float64((i/tileMapWidth)*tileSize))
-			// Translate game world inverse to player position
+			op.GeoM.Translate(float64((i%width)*tileSize), float64((i/width)*tileSize))
+			// Translate game world inverse to camera position
 			// so it moves in opposite direction
-			op.GeoM.Translate(float64(-g.player.position[0]), float64(-g.player.position[1]))
-			op.GeoM.Scale(4, 4)
-
-			sx := (t % tileXCount) * tileSize
-			sy := (t / tileXCount) * tileSize
-			screen.DrawImage(g.tilesImage.SubImage(image.Rect(sx, sy, sx+tileSize, sy+tileSize)).(*ebiten.Image), op)
+			op.GeoM.Concat(g.camera.GeoM())
+			screen.DrawImage(tile, op)
 		}
 	}
 }
 
-func getTileImage() *ebiten.Image {
-	// Decode an image from the image file's byte slice.
-	img, _, err := image.Decode(bytes.NewReader(images.Tiles_png))
+func getTileset() *Tileset {
+	ts, err := loadTileset("meadow", getAtlas())
 	if err != nil {
 		log.Fatal(err)
 	}
-	return ebiten.NewImageFromImage(img)
-}
-
-func getLayers() [][]int {
-	return [][]int{
-		{
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 218, 243, 243, 243, 243, 243, 243, 243, 243, 243, 218, 243, 244, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 244, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 219, 243, 243, 243, 219, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-			243, 218, 243, 243, 243, 243, 243, 243, 243, 243, 243, 244, 243, 243, 243,
-			243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243, 243,
-		},
-		{
-			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 26, 27, 28, 29, 30, 31, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 51, 52, 53, 54, 55, 56, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 76, 77, 78, 79, 80, 81, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 101, 102, 103, 104, 105, 106, 0, 0, 0, 0,
-
-			0, 0, 0, 0, 0, 126, 127, 128, 129, 130, 131, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 303, 303, 245, 242, 303, 303, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 245, 242, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 245, 242, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 245, 242, 0, 0, 0, 0, 0, 0,
-
-			0, 0, 0, 0, 0, 0, 0, 245, 242, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 245, 242, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 245, 0, 0, 0, 0, 0, 0, 0,
-			0, 0, 0, 0, 0, 0, 0, 245, 242, 0, 0, 0, 0, 0, 0,
-		},
-	}
+	return ts
 }
 
 func loadFont(size float64) (font.Face, error) {
@@ -0,0 +1,185 @@
+package mapgen
+
+import (
+	"fmt"
+	"slices"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Bits of a neighbour mask, set for neighbours of the same terrain
+const (
+	North uint8 = 1 << iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+// A diagonal only counts when both sides next to it do too, otherwise the
+// edge tiles on those sides already cover the corner. That leaves 47 masks.
+func reduceMask(m uint8) uint8 {
+	for _, c := range []struct{ corner, a, b uint8 }{
+		{NorthEast, North, East},
+		{SouthEast, South, East},
+		{SouthWest, South, West},
+		{NorthWest, North, West},
+	} {
+		if m&c.a == 0 || m&c.b == 0 {
+			m &^= c.corner
+		}
+	}
+	return m
+}
+
+// Every distinct reduced mask, in increasing order
+func EdgeMasks() []uint8 {
+	var masks []uint8
+	for m := range 256 {
+		if reduceMask(uint8(m)) == uint8(m) {
+			masks = append(masks, uint8(m))
+		}
+	}
+	return masks
+}
+
+// Atlas sprite name of the edge tile for a reduced mask
+func EdgeTileName(terrain string, mask uint8) string {
+	return fmt.Sprintf("tiles/%s-%d", terrain, mask)
+}
+
+// Tile ids of one terrain by reduced mask, each drawn with grass along the
+// sides and corners its mask leaves out
+type EdgeTiles map[uint8]int
+
+func edgeTilesFromNames(ids map[string]int, terrain string) (EdgeTiles, []string) {
+	tiles := EdgeTiles{}
+	var missing []string
+	for _, m := range EdgeMasks() {
+		name := EdgeTileName(terrain, m)
+		id, ok := ids[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		tiles[m] = id
+	}
+	return tiles, missing
+}
+
+// Reduced mask of the tile at x, y. Neighbours off the map count as the same
+// terrain, so a road running off the edge stays open there.
+func Mask(terrain []bool, width, height, x, y int) uint8 {
+	same := func(dx, dy int) bool {
+		nx, ny := x+dx, y+dy
+		if nx < 0 || ny < 0 || nx >= width || ny >= height {
+			return true
+		}
+		return terrain[ny*width+nx]
+	}
+	var m uint8
+	for _, n := range []struct {
+		bit    uint8
+		dx, dy int
+	}{
+		{North, 0, -1}, {NorthEast, 1, -1}, {East, 1, 0}, {SouthEast, 1, 1},
+		{South, 0, 1}, {SouthWest, -1, 1}, {West, -1, 0}, {NorthWest, -1, -1},
+	} {
+		if same(n.dx, n.dy) {
+			m |= n.bit
+		}
+	}
+	return reduceMask(m)
+}
+
+// Tile ids for the terrain, picked by what surrounds each tile and 0 where
+// there is none of it
+func Autotile(terrain []bool, width, height int, tiles EdgeTiles) []int {
+	ids := make([]int, len(terrain))
+	for i, t := range terrain {
+		if t {
+			ids[i] = tiles[Mask(terrain, width, height, i%width, i/width)]
+		}
+	}
+	return ids
+}
+
+// Tiles of a two tile wide road down every branch of the level's road. It
+// runs a tile on past each exit, into whatever enemies are headed for, so it
+// stays open there.
+func RoadFromPath(l *sim.Level) []bool {
+	var paths []sim.Path
+	exits := l.Exits()
+	for _, b := range l.Branches {
+		path := b.Path
+		if n := len(path); n >= 2 && slices.Contains(exits, path[n-1]) {
+			end := path[n-1].Sub(path[n-2])
+			if d := end.Len(); d > 0 {
+				path = append(slices.Clone(path), path[n-1].Add(end.Mul(1/d)))
+			}
+		}
+		paths = append(paths, path)
+	}
+	road := make([]bool, l.Width*l.Height)
+	for i := range road {
+		c := sim.Cell{X: i % l.Width, Y: i / l.Width}
+		for _, path := range paths {
+			// Straight runs pass 0.5 from the tiles on either side, turns up
+			// to 0.71 from the tile on their outside corner
+			if path.DistanceTo(c.Center()) < 0.75 {
+				road[i] = true
+			}
+		}
+	}
+	return road
+}
+
+// Replace the road tiles on a layer with ones autotiled from road, leaving
+// everything else on it alone. Tiles that are road but hold something else
+// are left too.
+func RetileRoad(layer []int, road []bool, width, height int, tiles EdgeTiles) {
+	roadIDs := map[int]bool{}
+	for _, id := range tiles {
+		roadIDs[id] = true
+	}
+	autotiled := Autotile(road, width, height, tiles)
+	for i := range layer {
+		switch {
+		case road[i] && (layer[i] == 0 || roadIDs[layer[i]]):
+			layer[i] = autotiled[i]
+		case !road[i] && roadIDs[layer[i]]:
+			layer[i] = 0
+		}
+	}
+}
+
+// Redraw the road on the second layer of a level along its branches, like
+// RetileRoad, and bring the rest of the level in line with it: decorations
+// no longer peek out from under the road, and tiles the road moved onto or
+// off of can or can't be built on like Generate decides. Tiles the level
+// opens or blocks by hand keep that unless the road changed under them.
+func RetileLevel(l *sim.Level, tiles Tiles) error {
+	if len(l.Layers) < 2 {
+		return fmt.Errorf("level has %d layers, the road goes on the second", len(l.Layers))
+	}
+	before := sim.BuildableTiles(l)
+	road := RoadFromPath(l)
+	RetileRoad(l.Layers[1], road, l.Width, l.Height, tiles.Road)
+
+	ground := l.Layers[0]
+	for i := range ground {
+		if road[i] && slices.Contains(tiles.Decorations, ground[i]) {
+			ground[i] = tiles.Grass
+		}
+	}
+	after := sim.BuildableTiles(l)
+	for i := range l.Buildable {
+		if l.Buildable[i] == before[i] || before[i] != after[i] {
+			l.Buildable[i] = after[i]
+		}
+	}
+	return nil
+}
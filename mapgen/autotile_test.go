@@ -0,0 +1,94 @@
+package mapgen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+func TestEdgeMasks(t *testing.T) {
+	masks := EdgeMasks()
+	if len(masks) != 47 {
+		t.Fatalf("%d edge masks, want 47", len(masks))
+	}
+	if !slices.IsSorted(masks) {
+		t.Error("edge masks aren't in increasing order")
+	}
+	for _, m := range masks {
+		if reduceMask(m) != m {
+			t.Errorf("edge mask %08b isn't reduced", m)
+		}
+	}
+}
+
+// Terrain of a 3x3 map from rows of '#' for the terrain and '.' for anything else
+func terrain(rows ...string) []bool {
+	var t []bool
+	for _, row := range rows {
+		for _, c := range row {
+			t = append(t, c == '#')
+		}
+	}
+	return t
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		terrain []bool
+		x, y    int
+		want    uint8
+	}{
+		{"alone", terrain("...", ".#.", "..."), 1, 1, 0},
+		{"surrounded", terrain("###", "###", "###"), 1, 1, 0xff},
+		{"corners without sides", terrain("#.#", ".#.", "#.#"), 1, 1, 0},
+		{"corner with one side", terrain("##.", ".#.", "..."), 1, 1, North},
+		{"corner with both sides", terrain("##.", "##.", "..."), 1, 1, North | West | NorthWest},
+		{"straight", terrain(".#.", ".#.", ".#."), 1, 1, North | South},
+		{"off the map at a corner", terrain("#..", "...", "..."), 0, 0, North | West | NorthWest},
+		{"off the map along a side", terrain("...", "...", ".#."), 1, 2, South},
+		{"off the map past a road", terrain(".#.", ".#.", ".#."), 1, 0, North | South},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mask(tt.terrain, 3, 3, tt.x, tt.y); got != tt.want {
+				t.Errorf("mask %08b, want %08b", got, tt.want)
+			}
+		})
+	}
+}
+
+// The maps shipped with the game are kept retiled, so doing it again is a no-op
+func TestRetileCommittedMaps(t *testing.T) {
+	tiles := loadTestTiles(t)
+	paths, err := filepath.Glob(filepath.Join("..", "assets", "maps", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no maps")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := sim.LoadLevel(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := levelBytes(t, l)
+			if err := RetileLevel(l, tiles); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(levelBytes(t, l), want) {
+				t.Error("retiling changed the map, run genmap -retile on it")
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Package mapgen makes playable maps: a road winding from the bottom edge to
+// a house, buildable ground along it and decorated grass everywhere else.
+// Maps come out as sim levels, ready to be written with sim.SaveLevel. Roads
+// are autotiled, so the same rules can redraw them on maps made by hand.
+package mapgen
+
+import (
+	"errors"
+	"fmt"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+// House size in tiles, the road ends at the door in the middle of its bottom row
+const houseWidth, houseHeight = 6, 5
+
+// Tile ids to paint with, as numbered by a tileset
+type Tiles struct {
+	Grass int
+	// Picked at random for some grass tiles
+	Decorations []int
+	Road        EdgeTiles
+	Fence       int
+	// House tiles row by row
+	House [houseHeight][houseWidth]int
+}
+
+// Look up the tiles a generator needs by their atlas sprite names, which
+// tileset files list in id order
+func TilesFromNames(names []string) (Tiles, error) {
+	ids := map[string]int{}
+	for id, name := range names {
+		ids[name] = id
+	}
+	var t Tiles
+	var missing []string
+	find := func(name string) int {
+		id, ok := ids[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return id
+	}
+	t.Grass = find("tiles/grass")
+	for _, name := range []string{"tiles/grass-flowers", "tiles/grass-tufts", "tiles/grass-stones"} {
+		t.Decorations = append(t.Decorations, find(name))
+	}
+	var missingRoad []string
+	t.Road, missingRoad = edgeTilesFromNames(ids, "road")
+	missing = append(missing, missingRoad...)
+	t.Fence = find("tiles/fence")
+	for y := range houseHeight {
+		for x := range houseWidth {
+			t.House[y][x] = find(fmt.Sprintf("tiles/house-%d-%d", y, x))
+		}
+	}
+	if len(missing) > 0 {
+		return t, fmt.Errorf("tileset is missing %v", missing)
+	}
+	return t, nil
+}
+
+type Options struct {
+	// Size in tiles, both even since the road is two tiles wide
+	Width, Height int
+	Seed          uint64
+	Tiles         Tiles
+	// Bounds of the road's length in tiles
+	MinPathLength, MaxPathLength float64
+	// Bounds of the number of tiles towers can go on
+	MinBuildable, MaxBuildable int
+	// Tiles further than this from the road are kept free of towers, 0 for no limit
+	BuildRange float64
+	// Share of grass tiles with a decoration, doubled away from the road
+	Decoration float64
+	// Roads tried before giving up on meeting the bounds
+	Attempts int
+}
+
+// Cells of the coarse grid the road is laid out on, each covers 2x2 tiles
+type block struct{ x, y int }
+
+// Make a map meeting the bounds in opts. The same options always give the same map.
+func Generate(opts Options) (*sim.Level, error) {
+	if opts.Width%2 != 0 || opts.Height%2 != 0 {
+		return nil, fmt.Errorf("size %dx%d isn't even", opts.Width, opts.Height)
+	}
+	// The house takes three blocks across and four down with its fence
+	if opts.Width < houseWidth || opts.Height < 10 {
+		return nil, fmt.Errorf("size %dx%d is too small for the house and a road", opts.Width, opts.Height)
+	}
+	rng := sim.NewRNG(opts.Seed)
+	var lastErr error = errors.New("no attempts")
+	for range max(1, opts.Attempts) {
+		l, err := generate(&rng, opts)
+		if err == nil {
+			return l, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("no map within bounds after %d attempts, last one: %w", opts.Attempts, lastErr)
+}
+
+// One attempt at a map, failing when it is out of bounds
+func generate(rng *sim.RNG, opts Options) (*sim.Level, error) {
+	w, h := opts.Width/2, opts.Height/2
+	// The house sits at the top with its fence, the road starts anywhere
+	// along the bottom and ends at the gap in the fence
+	hx := rng.Intn(w - 2)
+	blocked := map[block]bool{}
+	for x := hx; x < hx+3; x++ {
+		for y := range 4 {
+			blocked[block{x, y}] = true
+		}
+	}
+	goal := block{hx + 1, 3}
+	blocked[goal] = false
+	start := block{rng.Intn(w), h - 1}
+	if blocked[start] {
+		return nil, errors.New("road would start in the house")
+	}
+
+	road := walk(rng, w, h, blocked, start, goal)
+	if road == nil {
+		return nil, errors.New("no road to the house")
+	}
+
+	l := &sim.Level{
+		Width:  opts.Width,
+		Height: opts.Height,
+		Layers: [][]int{make([]int, opts.Width*opts.Height), make([]int, opts.Width*opts.Height)},
+		Seed:   opts.Seed,
+	}
+	l.Branches = []sim.Branch{{Path: roadPath(road, opts.Height)}}
+	if n := l.Branches[0].Path.Length(); n < opts.MinPathLength || opts.MaxPathLength > 0 && n > opts.MaxPathLength {
+		return nil, fmt.Errorf("road is %.0f tiles long", n)
+	}
+
+	// House and road on the second layer
+	objects := l.Layers[1]
+	for y := range houseHeight {
+		for x := range houseWidth {
+			objects[(y+1)*l.Width+2*hx+x] = opts.Tiles.House[y][x]
+		}
+	}
+	fenceRow := (houseHeight + 1) * l.Width
+	for _, x := range []int{0, 1, 4, 5} {
+		objects[fenceRow+2*hx+x] = opts.Tiles.Fence
+	}
+	RetileRoad(objects, RoadFromPath(l), l.Width, l.Height, opts.Tiles.Road)
+
+	l.Buildable = sim.BuildableTiles(l)
+	buildable := 0
+	for i := range l.Buildable {
+		c := sim.Cell{X: i % l.Width, Y: i / l.Width}
+		if opts.BuildRange > 0 && l.DistanceToPath(c.Center()) > opts.BuildRange {
+			l.Buildable[i] = false
+		}
+		if l.Buildable[i] {
+			buildable++
+		}
+	}
+	if buildable < opts.MinBuildable || opts.MaxBuildable > 0 && buildable > opts.MaxBuildable {
+		return nil, fmt.Errorf("%d tiles to build on", buildable)
+	}
+
+	// Grass everywhere, decorated more where nothing can be built
+	ground := l.Layers[0]
+	for i := range ground {
+		ground[i] = opts.Tiles.Grass
+		chance := opts.Decoration
+		if !l.Buildable[i] {
+			chance *= 2
+		}
+		if objects[i] == 0 && len(opts.Tiles.Decorations) > 0 && rng.Float64() < chance {
+			ground[i] = opts.Tiles.Decorations[rng.Intn(len(opts.Tiles.Decorations))]
+		}
+	}
+	return l, nil
+}
+
+// A random road from start to goal that never runs alongside itself, so there
+// is always room to build between its turns. Nil if there is none.
+func walk(rng *sim.RNG, w, h int, blocked map[block]bool, start, goal block) []block {
+	visited := map[block]bool{start: true}
+	road := []block{start}
+	// Dead ends are searched at most this many times, then the attempt is given up
+	budget := 20 * w * h
+	var step func(b block) bool
+	step = func(b block) bool {
+		if b == goal {
+			return true
+		}
+		if budget--; budget < 0 {
+			return false
+		}
+		next := []block{{b.x + 1, b.y}, {b.x - 1, b.y}, {b.x, b.y + 1}, {b.x, b.y - 1}}
+		for i := len(next) - 1; i > 0; i-- {
+			j := rng.Intn(i + 1)
+			next[i], next[j] = next[j], next[i]
+		}
+		for _, n := range next {
+			if n.x < 0 || n.y < 0 || n.x >= w || n.y >= h || visited[n] || blocked[n] {
+				continue
+			}
+			if touches(n, b, visited) {
+				continue
+			}
+			visited[n] = true
+			road = append(road, n)
+			if step(n) {
+				return true
+			}
+			road = road[:len(road)-1]
+			visited[n] = false
+		}
+		return false
+	}
+	if !step(start) {
+		return nil
+	}
+	return road
+}
+
+// Whether n is next to a visited block other than the one the road comes from
+func touches(n, from block, visited map[block]bool) bool {
+	for _, o := range []block{{n.x + 1, n.y}, {n.x - 1, n.y}, {n.x, n.y + 1}, {n.x, n.y - 1}} {
+		if o != from && visited[o] {
+			return true
+		}
+	}
+	return false
+}
+
+// Waypoints down the middle of the road, from below the bottom edge to the
+// house's fence, with a point only where the road turns
+func roadPath(road []block, height int) sim.Path {
+	center := func(b block) sim.Vec2 {
+		return sim.Vec2{X: float64(2*b.x + 1), Y: float64(2*b.y + 1)}
+	}
+	first, last := center(road[0]), center(road[len(road)-1])
+	points := []sim.Vec2{{X: first.X, Y: float64(height) + 0.5}}
+	for i := 0; i < len(road)-1; i++ {
+		p := center(road[i])
+		prev := points[len(points)-1]
+		next := center(road[i+1])
+		// Keep p only if the road changes direction there
+		if (prev.X == p.X) != (p.X == next.X) {
+			points = append(points, p)
+		}
+	}
+	// Enemies reach the house in the middle of the fence row
+	return append(points, sim.Vec2{X: last.X, Y: last.Y - 0.5})
+}
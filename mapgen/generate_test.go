@@ -0,0 +1,137 @@
+package mapgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Tiles of the game's own tileset
+func loadTestTiles(t *testing.T) Tiles {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "assets", "tilesets", "meadow.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f struct {
+		Tiles []string `json:"tiles"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	tiles, err := TilesFromNames(f.Tiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tiles
+}
+
+// Options genmap generates with by default
+func testOptions(t *testing.T, seed uint64) Options {
+	return Options{
+		Width:         16,
+		Height:        16,
+		Seed:          seed,
+		Tiles:         loadTestTiles(t),
+		MinPathLength: 20,
+		MaxPathLength: 60,
+		MinBuildable:  40,
+		BuildRange:    3.5,
+		Decoration:    0.1,
+		Attempts:      500,
+	}
+}
+
+func levelBytes(t *testing.T, l *sim.Level) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := sim.SaveLevel(&buf, l); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateIsSeeded(t *testing.T) {
+	generate := func(seed uint64) []byte {
+		l, err := Generate(testOptions(t, seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return levelBytes(t, l)
+	}
+	first := generate(7)
+	if !bytes.Equal(generate(7), first) {
+		t.Error("the same seed gave another map")
+	}
+	if bytes.Equal(generate(8), first) {
+		t.Error("another seed gave the same map")
+	}
+}
+
+func TestGenerateMeetsBounds(t *testing.T) {
+	opts := testOptions(t, 0)
+	opts.MinPathLength, opts.MaxPathLength = 30, 45
+	opts.MinBuildable, opts.MaxBuildable = 50, 80
+	for seed := range uint64(20) {
+		opts.Seed = seed
+		l, err := Generate(opts)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if n := l.Branches[0].Path.Length(); n < opts.MinPathLength || n > opts.MaxPathLength {
+			t.Errorf("seed %d: road is %.1f tiles long, want %.0f to %.0f", seed, n, opts.MinPathLength, opts.MaxPathLength)
+		}
+		buildable := 0
+		for _, b := range l.Buildable {
+			if b {
+				buildable++
+			}
+		}
+		if buildable < opts.MinBuildable || buildable > opts.MaxBuildable {
+			t.Errorf("seed %d: %d tiles to build on, want %d to %d", seed, buildable, opts.MinBuildable, opts.MaxBuildable)
+		}
+	}
+}
+
+func TestGenerateRejectsSize(t *testing.T) {
+	for _, size := range []struct{ width, height int }{
+		{15, 16},
+		{16, 17},
+		{4, 16},
+		{16, 8},
+	} {
+		opts := testOptions(t, 1)
+		opts.Width, opts.Height = size.width, size.height
+		if _, err := Generate(opts); err == nil {
+			t.Errorf("%dx%d map generated, want an error", size.width, size.height)
+		}
+	}
+}
+
+func TestGeneratedMapLoads(t *testing.T) {
+	l, err := Generate(testOptions(t, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What genmap takes from a template before writing the map
+	l.ID, l.Name = "generated", "Generated"
+	l.StartingGold, l.StartingLives = 100, 20
+	l.Waves = []sim.Wave{{Groups: []sim.WaveGroup{{Enemy: "grunt", Count: 5, Interval: 1}}}}
+
+	data := levelBytes(t, l)
+	loaded, err := sim.LoadLevel(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded.Buildable, l.Buildable) {
+		t.Error("tiles to build on changed when loaded")
+	}
+	if !bytes.Equal(levelBytes(t, loaded), data) {
+		t.Error("loaded map doesn't save as it was generated")
+	}
+}
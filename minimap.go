@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Minimap pixels per tile
+const minimapScale = 8
+
+var (
+	minimapEnemyColor    = hexToColor("e05040")
+	minimapViewportColor = hexToColor("dff4ff")
+)
+
+// The whole map in miniature in the bottom right corner. Clicking or dragging
+// on it moves the camera there.
+type Minimap struct {
+	container *widget.Container
+	graphic   *widget.Graphic
+	// Tiles drawn once, copied under the moving parts every frame
+	base  *ebiten.Image
+	image *ebiten.Image
+	// Set while the left mouse button pressed on the minimap is held
+	dragging bool
+}
+
+func (g *Game) newMinimap(res *uiResources) *Minimap {
+	size := g.mapSize().Mul(minimapScale)
+	m := &Minimap{
+		base:  ebiten.NewImage(size.X, size.Y),
+		image: ebiten.NewImage(size.X, size.Y),
+	}
+	width := g.world.Level().Width
+	for _, l := range g.world.Level().Layers {
+		for i, t := range l {
+			tile := g.tileset.Tile(t)
+			if tile == nil {
+				continue
+			}
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(float64(minimapScale)/tileSize, float64(minimapScale)/tileSize)
+			op.GeoM.Translate(float64((i%width)*minimapScale), float64((i/width)*minimapScale))
+			op.Filter = ebiten.FilterLinear
+			m.base.DrawImage(tile, op)
+		}
+	}
+
+	// The background makes the ui count as hovered, so clicks don't reach the map underneath
+	m.container = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionEnd,
+				HorizontalPosition: widget.AnchorLayoutPositionEnd,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(4)),
+		)),
+	)
+	m.graphic = widget.NewGraphic(
+		widget.GraphicOpts.Image(m.image),
+		widget.GraphicOpts.WidgetOpts(
+			widget.WidgetOpts.MouseButtonPressedHandler(func(args *widget.WidgetMouseButtonPressedEventArgs) {
+				if args.Button == ebiten.MouseButtonLeft {
+					m.dragging = true
+				}
+			}),
+		),
+	)
+	m.container.AddChild(m.graphic)
+	return m
+}
+
+func (m *Minimap) Update(g *Game) {
+	if m.dragging && !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		m.dragging = false
+	}
+	if m.dragging {
+		// Keep following the mouse even when it leaves the minimap while dragging
+		p := image.Pt(ebiten.CursorPosition()).Sub(m.graphic.GetWidget().Rect.Min)
+		g.centerOn(mgl32.Vec2{float32(p.X), float32(p.Y)}.Mul(float32(tileSize) / minimapScale))
+		g.player.follow = false
+	}
+
+	m.image.DrawImage(m.base, nil)
+	// Towers with the icons of the build bar
+	for _, t := range g.world.Towers() {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(float64(minimapScale)/towerIconSize, float64(minimapScale)/towerIconSize)
+		op.GeoM.Translate(float64(t.Cell.X*minimapScale), float64(t.Cell.Y*minimapScale))
+		op.Filter = ebiten.FilterLinear
+		m.image.DrawImage(towerIcon(t.Type.ID), op)
+	}
+	for _, e := range g.world.Enemies() {
+		vector.DrawFilledCircle(m.image, float32(e.Pos.X*minimapScale), float32(e.Pos.Y*minimapScale), 2, minimapEnemyColor, true)
+	}
+	if h := g.world.Hero(); h != nil && h.Alive() {
+		vector.DrawFilledCircle(m.image, float32(h.Pos.X*minimapScale), float32(h.Pos.Y*minimapScale), 3, heroStyle.color, true)
+	}
+
+	// The part of the world the camera shows
+	s := float32(minimapScale) / tileSize
+	view := g.camera.position.Mul(s)
+	vw, vh := float32(float64(g.screenSize.X)/g.camera.zoom)*s, float32(float64(g.screenSize.Y)/g.camera.zoom)*s
+	vector.StrokeRect(m.image, view[0], view[1], vw, vh, 1, minimapViewportColor, false)
+}
+
+// Move the camera so the world point is in the middle of the screen
+func (g *Game) centerOn(world mgl32.Vec2) {
+	half := mgl32.Vec2{float32(g.screenSize.X) / 2, float32(g.screenSize.Y) / 2}.Mul(float32(1 / g.camera.zoom))
+	g.player.position = world.Sub(half)
+	g.camera.position = g.player.position
+}
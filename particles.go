@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"log"
+	"math"
+	"math/rand/v2"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"icosahedron.com/tower-defense/sim"
+)
+
+const (
+	// Size of the particle pool, new particles are dropped while it is full
+	maxParticles = 2048
+	// Most particles started in one frame, so a burst of events can't stall a frame
+	maxParticlesPerFrame = 256
+)
+
+// How an emitter spawns particles and how they look over their life.
+// Distances are in game units, angles in degrees with 0 pointing right.
+type EmitterDef struct {
+	// Particles spawned at once
+	Burst int `json:"burst"`
+	// Particles per second for Duration seconds after the burst
+	Rate     float64 `json:"rate"`
+	Duration float64 `json:"duration"`
+	// Seconds, picked between min and max for each particle
+	Lifetime [2]float64 `json:"lifetime"`
+	Speed    [2]float64 `json:"speed"`
+	// Particles head within Spread/2 degrees either side of Direction
+	Direction float64 `json:"direction"`
+	Spread    float64 `json:"spread"`
+	// Random distance from the emitter position particles start at
+	Offset float64 `json:"offset"`
+	// Downwards acceleration in game units / second²
+	Gravity float64 `json:"gravity"`
+	// Side length at the start and end of life
+	Size [2]float64 `json:"size"`
+	// Hex colors blended evenly across the life
+	Colors []string `json:"colors"`
+	// Alpha at the start and end of life
+	Alpha    [2]float64 `json:"alpha"`
+	Additive bool       `json:"additive"`
+
+	colors []color.NRGBA
+}
+
+type particle struct {
+	def      *EmitterDef
+	pos, vel sim.Vec2
+	age      float64
+	life     float64
+}
+
+// An emitter still spawning at a rate
+type emitter struct {
+	def *EmitterDef
+	pos sim.Vec2
+	// Seconds of emission left, and the fraction of a particle owed from earlier ticks
+	left, owed float64
+}
+
+// CPU particles for impacts, deaths, construction and gold. Particles are
+// stored by value in a fixed pool and advance with simulation time, so they
+// freeze while the game is paused.
+type Particles struct {
+	defs      map[string]*EmitterDef
+	pool      [maxParticles]particle
+	live      int
+	emitters  []emitter
+	frameLeft int
+	op        ebiten.DrawImageOptions
+}
+
+func NewParticles() *Particles {
+	p := &Particles{defs: map[string]*EmitterDef{}, frameLeft: maxParticlesPerFrame}
+	f, err := embeddedAssets.Open("assets/particles.json")
+	if err != nil {
+		log.Println("Failed to open particles:", err)
+		return p
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&p.defs); err != nil {
+		log.Println("Failed to load particles:", err)
+		return p
+	}
+	// Emitters with a bad color are left out, the others still work
+	for name, d := range p.defs {
+		if err := d.parseColors(); err != nil {
+			log.Printf("Failed to load particles %s: %v", name, err)
+			delete(p.defs, name)
+		}
+	}
+	return p
+}
+
+// Parse Colors, white when there are none
+func (d *EmitterDef) parseColors() error {
+	for _, h := range d.Colors {
+		c, err := parseHexColor(h)
+		if err != nil {
+			return err
+		}
+		d.colors = append(d.colors, c)
+	}
+	if len(d.colors) == 0 {
+		d.colors = []color.NRGBA{{0xff, 0xff, 0xff, 0xff}}
+	}
+	return nil
+}
+
+// Start the emitter with the given name at pos in tiles, the fallback is used when it isn't defined
+func (p *Particles) Emit(name, fallback string, pos sim.Vec2) {
+	def := p.defs[name]
+	if def == nil {
+		def = p.defs[fallback]
+	}
+	if def == nil {
+		return
+	}
+	world := pos.Mul(tileSize)
+	for range def.Burst {
+		p.spawn(def, world)
+	}
+	if def.Rate > 0 && def.Duration > 0 {
+		p.emitters = append(p.emitters, emitter{def: def, pos: world, left: def.Duration})
+	}
+}
+
+func (p *Particles) spawn(def *EmitterDef, pos sim.Vec2) {
+	if p.live >= len(p.pool) || p.frameLeft <= 0 {
+		return
+	}
+	p.frameLeft--
+	angle := (def.Direction + (rand.Float64()-0.5)*def.Spread) * math.Pi / 180
+	speed := between(def.Speed)
+	offset := rand.Float64() * def.Offset
+	offsetAngle := rand.Float64() * 2 * math.Pi
+	p.pool[p.live] = particle{
+		def:  def,
+		pos:  pos.Add(sim.Vec2{X: math.Cos(offsetAngle), Y: math.Sin(offsetAngle)}.Mul(offset)),
+		vel:  sim.Vec2{X: math.Cos(angle), Y: math.Sin(angle)}.Mul(speed),
+		life: max(0.01, between(def.Lifetime)),
+	}
+	p.live++
+}
+
+func between(r [2]float64) float64 {
+	return r[0] + rand.Float64()*(r[1]-r[0])
+}
+
+// Start emitters for the events of the last simulation tick
+func (p *Particles) AddEvents(events []sim.Event) {
+	for _, e := range events {
+		switch e.Kind {
+		case sim.EventImpact:
+			p.Emit("impact-"+e.Type, "impact", e.Pos)
+		case sim.EventDeath:
+			p.Emit("death-"+e.Type, "death", e.Pos)
+		case sim.EventBuild:
+			p.Emit("build", "", e.Pos)
+		case sim.EventGold:
+			p.Emit("gold", "", e.Pos)
+		case sim.EventHeroAbility:
+			p.Emit("ability-"+e.Type, "ability", e.Pos)
+		case sim.EventHeroLevel:
+			p.Emit("levelup", "", e.Pos)
+		}
+	}
+}
+
+// Advance by one simulation tick
+func (p *Particles) Step() {
+	const dt = sim.TickDuration
+	emitters := p.emitters[:0]
+	for _, e := range p.emitters {
+		e.owed += e.def.Rate * dt
+		for ; e.owed >= 1; e.owed-- {
+			p.spawn(e.def, e.pos)
+		}
+		e.left -= dt
+		if e.left > 0 {
+			emitters = append(emitters, e)
+		}
+	}
+	p.emitters = emitters
+
+	for i := 0; i < p.live; {
+		pt := &p.pool[i]
+		pt.age += dt
+		if pt.age >= pt.life {
+			// Swap the last live particle into the free slot
+			p.live--
+			p.pool[i] = p.pool[p.live]
+			continue
+		}
+		pt.vel.Y += pt.def.Gravity * dt
+		pt.pos = pt.pos.Add(pt.vel.Mul(dt))
+		i++
+	}
+}
+
+func (p *Particles) Draw(screen *ebiten.Image, camera *Camera) {
+	p.frameLeft = maxParticlesPerFrame
+	cam := camera.GeoM()
+	for i := range p.live {
+		pt := &p.pool[i]
+		t := pt.age / pt.life
+		size := pt.def.Size[0] + (pt.def.Size[1]-pt.def.Size[0])*t
+
+		op := &p.op
+		op.GeoM.Reset()
+		op.ColorScale.Reset()
+		// The white source image is one pixel, so scaling by size gives the side length
+		op.GeoM.Scale(size, size)
+		op.GeoM.Translate(pt.pos.X-size/2, pt.pos.Y-size/2)
+		op.GeoM.Concat(cam)
+		op.ColorScale.ScaleWithColor(colorAt(pt.def.colors, t))
+		op.ColorScale.ScaleAlpha(float32(pt.def.Alpha[0] + (pt.def.Alpha[1]-pt.def.Alpha[0])*t))
+		op.Blend = ebiten.BlendSourceOver
+		if pt.def.Additive {
+			op.Blend = ebiten.BlendLighter
+		}
+		screen.DrawImage(whiteSubImage, op)
+	}
+}
+
+// Color a fraction t through the gradient
+func colorAt(colors []color.NRGBA, t float64) color.NRGBA {
+	if len(colors) == 1 {
+		return colors[0]
+	}
+	f := t * float64(len(colors)-1)
+	i := min(int(f), len(colors)-2)
+	f -= float64(i)
+	a, b := colors[i], colors[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f)
+	}
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Pointer id used for the mouse, touch ids are never negative
+const mousePointerID = -1
+
+// Enum of things that can happen to a pointer
+type PointerEventKind string
+
+const (
+	PointerDown PointerEventKind = "down"
+	PointerMove PointerEventKind = "move"
+	PointerUp   PointerEventKind = "up"
+)
+
+// A mouse or touch pointer changing, in screen coordinates
+type PointerEvent struct {
+	ID       int
+	Kind     PointerEventKind
+	Position image.Point
+	Touch    bool
+}
+
+// Produces the pointer events that happened since the previous call
+type PointerSource interface {
+	AppendEvents(events []PointerEvent) []PointerEvent
+}
+
+// Reads the mouse and all touches from Ebiten. The mouse is pressed by the
+// buttons bound to ActionSelect, so rebinding it moves taps to another button.
+type ebitenPointerSource struct {
+	input    *InputMap
+	mouse    mousePointer
+	touches  map[ebiten.TouchID]image.Point
+	touchIDs []ebiten.TouchID
+}
+
+func newEbitenPointerSource(input *InputMap) *ebitenPointerSource {
+	return &ebitenPointerSource{
+		input:   input,
+		touches: map[ebiten.TouchID]image.Point{},
+	}
+}
+
+// The mouse as a pointer, held down while any mouse button bound to select is
+type mousePointer struct {
+	down bool
+	last image.Point
+}
+
+func (m *mousePointer) appendEvents(events []PointerEvent, bindings []Binding, isPressed func(ebiten.MouseButton) bool, pos image.Point) []PointerEvent {
+	pressed := false
+	for _, b := range bindings {
+		if b.Kind == BindingMouse && isPressed(b.Mouse) {
+			pressed = true
+		}
+	}
+	switch {
+	case pressed && !m.down:
+		events = append(events, PointerEvent{ID: mousePointerID, Kind: PointerDown, Position: pos})
+	case !pressed && m.down:
+		events = append(events, PointerEvent{ID: mousePointerID, Kind: PointerUp, Position: pos})
+	case pressed && pos != m.last:
+		events = append(events, PointerEvent{ID: mousePointerID, Kind: PointerMove, Position: pos})
+	}
+	m.down = pressed
+	m.last = pos
+	return events
+}
+
+func (s *ebitenPointerSource) AppendEvents(events []PointerEvent) []PointerEvent {
+	mouse := image.Pt(ebiten.CursorPosition())
+	events = s.mouse.appendEvents(events, s.input.Bindings(ActionSelect), ebiten.IsMouseButtonPressed, mouse)
+
+	s.touchIDs = inpututil.AppendJustReleasedTouchIDs(s.touchIDs[:0])
+	for _, id := range s.touchIDs {
+		p := image.Pt(inpututil.TouchPositionInPreviousTick(id))
+		events = append(events, PointerEvent{ID: int(id), Kind: PointerUp, Position: p, Touch: true})
+		delete(s.touches, id)
+	}
+	s.touchIDs = ebiten.AppendTouchIDs(s.touchIDs[:0])
+	for _, id := range s.touchIDs {
+		p := image.Pt(ebiten.TouchPosition(id))
+		last, ok := s.touches[id]
+		if !ok {
+			events = append(events, PointerEvent{ID: int(id), Kind: PointerDown, Position: p, Touch: true})
+		} else if p != last {
+			events = append(events, PointerEvent{ID: int(id), Kind: PointerMove, Position: p, Touch: true})
+		}
+		s.touches[id] = p
+	}
+	return events
+}
+
+// Replays queued events, used to drive Gestures without real devices
+type SyntheticPointerSource struct {
+	pending []PointerEvent
+}
+
+func (s *SyntheticPointerSource) Push(events ...PointerEvent) {
+	s.pending = append(s.pending, events...)
+}
+
+func (s *SyntheticPointerSource) AppendEvents(events []PointerEvent) []PointerEvent {
+	events = append(events, s.pending...)
+	s.pending = s.pending[:0]
+	return events
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+const profileFileName = "profile.json"
+
+// Best result on one level
+type LevelProgress struct {
+	Completed bool `json:"completed"`
+	BestScore int  `json:"bestScore"`
+	Stars     int  `json:"stars"`
+	// Most waves started in one endless game on the level's map
+	BestEndlessWave int `json:"bestEndlessWave,omitempty"`
+	// Id of the hardest difficulty the level was won on
+	Hardest string `json:"hardest,omitempty"`
+}
+
+// Best result of one daily challenge
+type DailyResult struct {
+	Level     string             `json:"level"`
+	Modifiers []string           `json:"modifiers"`
+	Attempts  int                `json:"attempts"`
+	Won       bool               `json:"won"`
+	Best      sim.ScoreBreakdown `json:"best"`
+}
+
+// Campaign progress of the local player
+type Profile struct {
+	levels map[string]LevelProgress
+	// Keyed by the challenge's date
+	daily map[string]DailyResult
+}
+
+// On disk representation of Profile
+type profileFile struct {
+	Levels map[string]LevelProgress `json:"levels"`
+	Daily  map[string]DailyResult   `json:"daily,omitempty"`
+}
+
+func loadProfile() (*Profile, error) {
+	p := &Profile{levels: map[string]LevelProgress{}, daily: map[string]DailyResult{}}
+	path, err := configPath(profileFileName)
+	if err != nil {
+		return p, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	var f profileFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return p, err
+	}
+	for id, l := range f.Levels {
+		p.levels[id] = l
+	}
+	for date, r := range f.Daily {
+		p.daily[date] = r
+	}
+	return p, nil
+}
+
+func (p *Profile) save() error {
+	path, err := configPath(profileFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(profileFile{Levels: p.levels, Daily: p.daily}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (p *Profile) Progress(id string) LevelProgress {
+	return p.levels[id]
+}
+
+// The first level is always open, every other one opens once the one before it is completed
+func (p *Profile) Unlocked(campaign []*sim.Level, i int) bool {
+	return i == 0 || p.levels[campaign[i-1].ID].Completed
+}
+
+// First level that is unlocked but not completed yet, or the last one when all are done
+func (p *Profile) NextLevel(campaign []*sim.Level) int {
+	for i, l := range campaign {
+		if !p.levels[l.ID].Completed {
+			return i
+		}
+	}
+	return len(campaign) - 1
+}
+
+// Keep the best score and rating of a finished game, only wins count. For
+// endless games keep the furthest wave instead, whether finished or not.
+func (p *Profile) Record(w *sim.World) {
+	id := w.Level().ID
+	l := p.levels[id]
+	if w.Endless() {
+		l.BestEndlessWave = max(l.BestEndlessWave, w.WavesStarted())
+		p.levels[id] = l
+		return
+	}
+	if !w.Won() {
+		return
+	}
+	l.Completed = true
+	l.BestScore = max(l.BestScore, w.Score())
+	l.Stars = max(l.Stars, w.Stars())
+	difficulties := w.Content().Difficulties
+	if slices.Index(difficulties, w.Difficulty()) > slices.Index(difficulties, l.Hardest) {
+		l.Hardest = w.Difficulty()
+	}
+	p.levels[id] = l
+}
+
+func (p *Profile) Daily(date string) (DailyResult, bool) {
+	r, ok := p.daily[date]
+	return r, ok
+}
+
+// Count a finished attempt at a daily challenge and keep the best score
+func (p *Profile) RecordDaily(ch sim.Challenge, w *sim.World) {
+	r, ok := p.daily[ch.Date]
+	b := w.ScoreBreakdown()
+	if !ok || b.Total > r.Best.Total {
+		r.Best = b
+	}
+	r.Level = ch.Level
+	r.Modifiers = ch.Modifiers
+	r.Attempts++
+	r.Won = r.Won || w.Won()
+	p.daily[ch.Date] = r
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+const replayDirName = "replays"
+
+// Write the game played so far to a new file in the replays folder and return its path
+func (g *Game) saveReplay() (string, error) {
+	r, err := g.world.Replay()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s.json", r.Level, time.Now().Format("20060102-150405"))
+	path, err := configPath(filepath.Join(replayDirName, name))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return path, sim.WriteReplay(f, r)
+}
+
+// Watch a replay file instead of playing. Player commands are ignored until
+// another level is started.
+func (g *Game) startPlayback(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := sim.ReadReplay(f)
+	if err != nil {
+		return err
+	}
+	for i, l := range g.campaign {
+		if l.ID != r.Level {
+			continue
+		}
+		p, err := sim.NewPlayback(r, l, sim.DefaultContent())
+		if err != nil {
+			return err
+		}
+		g.startWorld(i, p.World(), p)
+		return nil
+	}
+	return fmt.Errorf("replay is for unknown level %s", r.Level)
+}
+
+// Position in the replay with a slider to jump around, shown during playback
+type ReplayBar struct {
+	container *widget.Container
+	label     *widget.Text
+	slider    *widget.Slider
+	// Second the slider was last moved to by Update, other values come from the player
+	shown int
+	// Second the player dragged the slider to, applied on the next update
+	seekTo  int
+	seeking bool
+}
+
+func (g *Game) newReplayBar(res *uiResources, face font.Face) *ReplayBar {
+	b := &ReplayBar{}
+	b.container = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionEnd,
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(8)),
+			widget.RowLayoutOpts.Spacing(10),
+		)),
+	)
+
+	b.label = widget.NewText(
+		widget.TextOpts.Text("", face, res.text.idleColor),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	)
+	b.container.AddChild(b.label)
+
+	length := g.playback.Replay().Ticks / sim.TicksPerSecond
+	b.slider = widget.NewSlider(
+		widget.SliderOpts.Direction(widget.DirectionHorizontal),
+		widget.SliderOpts.MinMax(0, max(1, length)),
+		widget.SliderOpts.Images(res.slider.trackImage, res.slider.handle),
+		widget.SliderOpts.FixedHandleSize(res.slider.handleSize),
+		widget.SliderOpts.TrackPadding(res.slider.trackPadding),
+		widget.SliderOpts.WidgetOpts(
+			widget.WidgetOpts.MinSize(300, 20),
+			widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+				Position: widget.RowLayoutPositionCenter,
+			}),
+		),
+		widget.SliderOpts.ChangedHandler(func(args *widget.SliderChangedEventArgs) {
+			if args.Current != b.shown {
+				b.seekTo = args.Current
+				b.seeking = true
+			}
+		}),
+	)
+	b.slider.Current = 0
+	b.container.AddChild(b.slider)
+	return b
+}
+
+func (b *ReplayBar) Update(g *Game) {
+	if b.seeking {
+		b.seeking = false
+		if err := g.playback.Seek(b.seekTo * sim.TicksPerSecond); err != nil {
+			log.Println("Failed to seek replay:", err)
+		}
+		g.world = g.playback.World()
+	}
+	now := g.world.Tick() / sim.TicksPerSecond
+	length := g.playback.Replay().Ticks / sim.TicksPerSecond
+	b.label.Label = fmt.Sprintf("Replay %02d:%02d / %02d:%02d", now/60, now%60, length/60, length%60)
+	b.shown = now
+	b.slider.Current = now
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
 	"strconv"
 
@@ -43,6 +44,8 @@ type uiResources struct {
 	label      *labelResources
 	panel      *panelResources
 	textInput  *textInputResources
+	toolTip    *toolTipResources
+	slider     *sliderResources
 }
 
 type textResources struct {
@@ -70,7 +73,21 @@ type panelResources struct {
 	padding widget.Insets
 }
 
+type toolTipResources struct {
+	background *image.NineSlice
+	color      color.Color
+	padding    widget.Insets
+}
+
+type sliderResources struct {
+	trackImage   *widget.SliderTrackImage
+	handle       *widget.ButtonImage
+	handleSize   int
+	trackPadding widget.Insets
+}
+
 type textInputResources struct {
+	image   *widget.TextInputImage
 	padding widget.Insets
 	color   *widget.TextInputColor
 }
@@ -109,6 +126,8 @@ func newUIResources() (*uiResources, error) {
 		checkbox:  checkbox,
 		panel:     panel,
 		textInput: textInput,
+		toolTip:   newToolTipResources(),
+		slider:    newSliderResources(button),
 	}, nil
 }
 
@@ -227,9 +246,35 @@ func newPanelResources() (*panelResources, error) {
 		},
 	}, nil
 }
+func newToolTipResources() *toolTipResources {
+	return &toolTipResources{
+		background: image.NewNineSliceColor(hexToColor(toolTipColor)),
+		color:      hexToColor(toolTipColor),
+		padding:    widget.NewInsetsSimple(8),
+	}
+}
+
+// The handle reuses the button images
+func newSliderResources(button *buttonResources) *sliderResources {
+	return &sliderResources{
+		trackImage: &widget.SliderTrackImage{
+			Idle:     image.NewNineSliceColor(hexToColor(listDisabledSelectedBackground)),
+			Hover:    image.NewNineSliceColor(hexToColor(listSelectedBackground)),
+			Disabled: image.NewNineSliceColor(hexToColor(listDisabledSelectedBackground)),
+		},
+		handle:       button.image,
+		handleSize:   20,
+		trackPadding: widget.Insets{Top: 2, Bottom: 2},
+	}
+}
+
 func newTextInputResources() (*textInputResources, error) {
 
 	return &textInputResources{
+		image: &widget.TextInputImage{
+			Idle:     image.NewNineSliceColor(hexToColor(listFocusedBackground)),
+			Disabled: image.NewNineSliceColor(hexToColor(separatorColor)),
+		},
 
 		padding: widget.Insets{
 			Left:   8,
@@ -247,16 +292,26 @@ func newTextInputResources() (*textInputResources, error) {
 	}, nil
 }
 
+// Color of the built in styles, which are known to parse
 func hexToColor(h string) color.Color {
-	u, err := strconv.ParseUint(h, 16, 0)
+	c, err := parseHexColor(h)
 	if err != nil {
 		panic(err)
 	}
+	return c
+}
+
+// Opaque color from six hex digits, like "c0503a"
+func parseHexColor(h string) (color.NRGBA, error) {
+	u, err := strconv.ParseUint(h, 16, 32)
+	if err != nil || len(h) != 6 {
+		return color.NRGBA{}, fmt.Errorf("color %q isn't six hex digits", h)
+	}
 
 	return color.NRGBA{
 		R: uint8(u & 0xff0000 >> 16),
 		G: uint8(u & 0xff00 >> 8),
 		B: uint8(u & 0xff),
 		A: 255,
-	}
+	}, nil
 }
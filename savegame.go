@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"icosahedron.com/tower-defense/sim"
+)
+
+const saveFileName = "save.json"
+
+// Write the running game to the save slot
+func (g *Game) saveGame() error {
+	path, err := configPath(saveFileName)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := g.world.WriteSave(&b); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// Replace the running game with the one in the save slot
+func (g *Game) loadGame() error {
+	path, err := configPath(saveFileName)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s, err := sim.ReadSave(f)
+	if err != nil {
+		return err
+	}
+	for i, l := range g.campaign {
+		if l.ID != s.LevelID() {
+			continue
+		}
+		w, err := s.Restore(l, sim.DefaultContent())
+		if err != nil {
+			return err
+		}
+		g.startWorld(i, w, nil)
+		// Results of today's challenge still count when it was saved and loaded
+		if ch := g.todaysChallenge(); ch.Level == s.LevelID() && ch.Seed == s.Seed() {
+			g.daily = &ch
+		}
+		return nil
+	}
+	return fmt.Errorf("save is for unknown level %s", s.LevelID())
+}
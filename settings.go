@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"icosahedron.com/tower-defense/sim"
+)
+
+const (
+	configDirName    = "icosahedron-tower-defense"
+	settingsFileName = "settings.json"
+)
+
+type Settings struct {
+	showFPS        bool
+	vSynch         bool
+	showHealthBars bool
+	bindings       map[Action][]Binding
+	// Picked on the level setup screen and applied to every level started
+	difficulty string
+	modifiers  []string
+}
+
+// On disk representation of Settings
+type settingsFile struct {
+	ShowFPS        bool                `json:"showFPS"`
+	VSynch         bool                `json:"vSynch"`
+	ShowHealthBars bool                `json:"showHealthBars"`
+	Bindings       map[Action][]string `json:"bindings,omitempty"`
+	Difficulty     string              `json:"difficulty,omitempty"`
+	Modifiers      []string            `json:"modifiers,omitempty"`
+}
+
+func defaultSettings() *Settings {
+	return &Settings{
+		showFPS:        false,
+		vSynch:         ebiten.IsVsyncEnabled(),
+		showHealthBars: true,
+		bindings:       defaultBindings(),
+		difficulty:     sim.DefaultDifficulty,
+	}
+}
+
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configDirName, name), nil
+}
+
+// Loads the settings file, falling back to the defaults for anything missing
+func loadSettings() (*Settings, error) {
+	s := defaultSettings()
+	path, err := configPath(settingsFileName)
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	// Settings missing from older files keep their defaults
+	f := settingsFile{ShowHealthBars: s.showHealthBars, Difficulty: s.difficulty}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return s, err
+	}
+	s.showFPS = f.ShowFPS
+	s.vSynch = f.VSynch
+	s.showHealthBars = f.ShowHealthBars
+	s.difficulty = f.Difficulty
+	s.modifiers = f.Modifiers
+	for action, names := range f.Bindings {
+		var bindings []Binding
+		for _, name := range names {
+			b, err := parseBinding(name)
+			if err != nil {
+				log.Println("Ignoring binding:", err)
+				continue
+			}
+			bindings = append(bindings, b)
+		}
+		s.bindings[action] = bindings
+	}
+	return s, nil
+}
+
+func (s *Settings) save() error {
+	path, err := configPath(settingsFileName)
+	if err != nil {
+		return err
+	}
+	f := settingsFile{
+		ShowFPS:        s.showFPS,
+		VSynch:         s.vSynch,
+		ShowHealthBars: s.showHealthBars,
+		Bindings:       map[Action][]string{},
+		Difficulty:     s.difficulty,
+		Modifiers:      s.modifiers,
+	}
+	for action, bindings := range s.bindings {
+		names := []string{}
+		for _, b := range bindings {
+			names = append(names, b.String())
+		}
+		f.Bindings[action] = names
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Difficulty first, then the modifiers, as applied to levels
+func (s *Settings) levelModifiers() []string {
+	return append([]string{s.difficulty}, s.modifiers...)
+}
@@ -0,0 +1,189 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// A stretch of road walked from its first point to its last. Enemies reaching
+// the end of a branch carry on along one starting at the same point, so roads
+// fork where several branches start at one point and merge where several end
+// at one. Branches starting where none ends are spawns, and ones ending where
+// none starts lead to exits.
+type Branch struct {
+	Path Path
+	// How likely enemies at a fork are to take this branch, relative to the
+	// others starting there. 0 counts as 1.
+	Weight float64
+}
+
+func (b Branch) weight() float64 {
+	if b.Weight > 0 {
+		return b.Weight
+	}
+	return 1
+}
+
+func (b Branch) first() Vec2 {
+	return b.Path[0]
+}
+
+func (b Branch) last() Vec2 {
+	return b.Path[len(b.Path)-1]
+}
+
+// Indices of the branches starting at p, in the order they are listed
+func (l *Level) branchesFrom(p Vec2) []int {
+	var from []int
+	for i, b := range l.Branches {
+		if len(b.Path) > 0 && b.first() == p {
+			from = append(from, i)
+		}
+	}
+	return from
+}
+
+func (l *Level) endsAt(p Vec2) bool {
+	for _, b := range l.Branches {
+		if len(b.Path) > 0 && b.last() == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Points enemies come in at, in the order of the first branch from each.
+// Wave groups pick one by its index.
+func (l *Level) Spawns() []Vec2 {
+	var spawns []Vec2
+	for _, b := range l.Branches {
+		if len(b.Path) > 0 && !l.endsAt(b.first()) && !slices.Contains(spawns, b.first()) {
+			spawns = append(spawns, b.first())
+		}
+	}
+	return spawns
+}
+
+// Points enemies leave at, in the order of the first branch to each
+func (l *Level) Exits() []Vec2 {
+	var exits []Vec2
+	for _, b := range l.Branches {
+		if len(b.Path) > 0 && len(l.branchesFrom(b.last())) == 0 && !slices.Contains(exits, b.last()) {
+			exits = append(exits, b.last())
+		}
+	}
+	return exits
+}
+
+// Shortest distance from v to any branch
+func (l *Level) DistanceToPath(v Vec2) float64 {
+	d := math.Inf(1)
+	for _, b := range l.Branches {
+		d = min(d, b.Path.DistanceTo(v))
+	}
+	return d
+}
+
+// Check that every branch has two points, that there is a spawn and that no
+// road leads back onto itself, so every route ends at an exit
+func (l *Level) CheckBranches() error {
+	if len(l.Branches) == 0 {
+		return errors.New("no path")
+	}
+	for i, b := range l.Branches {
+		if len(b.Path) < 2 {
+			return fmt.Errorf("branch %d has fewer than two points", i)
+		}
+		if b.Weight < 0 {
+			return fmt.Errorf("branch %d has a negative weight", i)
+		}
+	}
+	if len(l.Spawns()) == 0 {
+		return errors.New("no branch starts where none ends")
+	}
+	// Depth first, a branch met again while its continuations are still
+	// being walked closes a loop
+	const (
+		unvisited = iota
+		walking
+		done
+	)
+	state := make([]int, len(l.Branches))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case walking:
+			return fmt.Errorf("branch %d leads back onto itself", i)
+		case done:
+			return nil
+		}
+		state[i] = walking
+		for _, next := range l.branchesFrom(l.Branches[i].last()) {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		return nil
+	}
+	for i := range l.Branches {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Branches taken from a spawn to an exit, picking at each fork with pick,
+// which gets the weights of the branches to choose from. It is only called
+// where there is a choice.
+func (l *Level) route(spawn int, pick func(weights []float64) int) []int {
+	spawns := l.Spawns()
+	if len(spawns) == 0 {
+		return nil
+	}
+	p := spawns[max(0, spawn)%len(spawns)]
+	var taken []int
+	// Bounded in case of a loop, which levels from LoadLevel never have
+	for range len(l.Branches) {
+		from := l.branchesFrom(p)
+		if len(from) == 0 {
+			break
+		}
+		next := from[0]
+		if len(from) > 1 {
+			weights := make([]float64, len(from))
+			for i, b := range from {
+				weights[i] = l.Branches[b].weight()
+			}
+			next = from[pick(weights)]
+		}
+		taken = append(taken, next)
+		p = l.Branches[next].last()
+	}
+	return taken
+}
+
+// Path along the branches taken, each starting where the one before ends
+func (l *Level) routePath(branches []int) (Path, error) {
+	var path Path
+	for i, b := range branches {
+		if b < 0 || b >= len(l.Branches) || len(l.Branches[b].Path) == 0 {
+			return nil, fmt.Errorf("no branch %d", b)
+		}
+		branch := l.Branches[b].Path
+		if i > 0 {
+			if branch[0] != path[len(path)-1] {
+				return nil, fmt.Errorf("branch %d doesn't start where branch %d ends", b, branches[i-1])
+			}
+			branch = branch[1:]
+		}
+		path = append(path, branch...)
+	}
+	if len(path) < 2 {
+		return nil, errors.New("route is too short")
+	}
+	return path, nil
+}
@@ -0,0 +1,173 @@
+package sim
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func branch(weight float64, points ...Vec2) Branch {
+	return Branch{Path: Path(points), Weight: weight}
+}
+
+var (
+	forkStart = Vec2{X: 8, Y: 15.5}
+	forkSplit = Vec2{X: 8, Y: 12.5}
+	forkMerge = Vec2{X: 8, Y: 9.5}
+	forkEnd   = Vec2{X: 8, Y: 7.5}
+)
+
+// A road that forks in two and merges again, the left way three times as
+// likely as the right
+func forkBranches() []Branch {
+	return []Branch{
+		branch(0, forkStart, forkSplit),
+		branch(3, forkSplit, Vec2{X: 6, Y: 11}, forkMerge),
+		branch(1, forkSplit, Vec2{X: 10, Y: 11}, forkMerge),
+		branch(0, forkMerge, forkEnd),
+	}
+}
+
+func TestCheckBranches(t *testing.T) {
+	a, b, c := Vec2{X: 1, Y: 1}, Vec2{X: 2, Y: 1}, Vec2{X: 3, Y: 1}
+	tests := []struct {
+		name     string
+		branches []Branch
+		// Part of the error, empty when the branches are fine
+		err string
+	}{
+		{"single path", []Branch{branch(0, a, b, c)}, ""},
+		{"fork and merge", forkBranches(), ""},
+		{"two spawns merging", []Branch{branch(0, a, b), branch(0, Vec2{X: 2, Y: 5}, b), branch(0, b, c)}, ""},
+		{"zero total weight at a fork", []Branch{branch(0, a, b), branch(0, b, c), branch(0, b, Vec2{X: 2, Y: 3})}, ""},
+		{"no branches", nil, "no path"},
+		{"dangling point", []Branch{branch(0, a, b), branch(0, b)}, "fewer than two points"},
+		{"negative weight", []Branch{branch(0, a, b), branch(-1, b, c), branch(1, b, Vec2{X: 2, Y: 3})}, "negative weight"},
+		{"closed loop", []Branch{branch(0, a, b), branch(0, b, a)}, "no branch starts where none ends"},
+		{"loop after a spawn", []Branch{branch(0, a, b), branch(0, b, c), branch(0, c, Vec2{X: 3, Y: 3}, b)}, "leads back onto itself"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Level{Branches: tt.branches}
+			err := l.CheckBranches()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case tt.err != "" && err == nil:
+				t.Fatalf("no error, want one about %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("error %q, want one about %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSpawnsAndExits(t *testing.T) {
+	l := &Level{Branches: append(forkBranches(), branch(0, Vec2{X: 2, Y: 15.5}, forkMerge), branch(0, forkSplit, Vec2{X: 14, Y: 12.5}))}
+	if got, want := l.Spawns(), []Vec2{forkStart, {X: 2, Y: 15.5}}; !slices.Equal(got, want) {
+		t.Errorf("spawns %v, want %v", got, want)
+	}
+	if got, want := l.Exits(), []Vec2{forkEnd, {X: 14, Y: 12.5}}; !slices.Equal(got, want) {
+		t.Errorf("exits %v, want %v", got, want)
+	}
+}
+
+func TestRouteOnlyPicksAtForks(t *testing.T) {
+	l := &Level{Branches: forkBranches()}
+	var asked [][]float64
+	route := l.route(0, func(weights []float64) int {
+		asked = append(asked, weights)
+		return 1
+	})
+	if want := []int{0, 2, 3}; !slices.Equal(route, want) {
+		t.Errorf("route %v, want %v", route, want)
+	}
+	if len(asked) != 1 || !slices.Equal(asked[0], []float64{3, 1}) {
+		t.Errorf("asked to pick from %v, want only [3 1]", asked)
+	}
+	path, err := l.routePath(route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Path{forkStart, forkSplit, {X: 10, Y: 11}, forkMerge, forkEnd}); !slices.Equal(path, want) {
+		t.Errorf("path %v, want %v", path, want)
+	}
+}
+
+// Branches taken by the enemies of a world on meadow with forkBranches
+func spawnedRoutes(t *testing.T, seed uint64, n int) [][]int {
+	t.Helper()
+	l := loadTestLevel(t, "meadow")
+	l.Branches = forkBranches()
+	l.Seed = seed
+	w := NewWorld(l, DefaultContent())
+	var routes [][]int
+	for range n {
+		w.spawnEnemy("grunt", 0)
+		routes = append(routes, w.enemies[len(w.enemies)-1].branches)
+	}
+	return routes
+}
+
+func TestForkPicksAreSeeded(t *testing.T) {
+	const n = 400
+	first := spawnedRoutes(t, 7, n)
+	again := spawnedRoutes(t, 7, n)
+	left := 0
+	for i := range first {
+		if !slices.Equal(first[i], again[i]) {
+			t.Fatalf("enemy %d took %v, then %v on the same seed", i, first[i], again[i])
+		}
+		if first[i][1] == 1 {
+			left++
+		}
+	}
+	// Weighted three to one, so well over half go left but not all of them
+	if left < n*6/10 || left > n*9/10 {
+		t.Errorf("%d of %d enemies went left, want about three quarters", left, n)
+	}
+
+	other := spawnedRoutes(t, 8, n)
+	same := true
+	for i := range first {
+		same = same && slices.Equal(first[i], other[i])
+	}
+	if same {
+		t.Error("another seed picked the same branches")
+	}
+}
+
+func TestSaveMigratesFromVersion4(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "save_v4.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := ReadSave(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := loadTestLevel(t, "meadow")
+	w, err := s.Restore(l, DefaultContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Enemies()) == 0 {
+		t.Fatal("no enemies in the save")
+	}
+	// Saved before branches, so everyone walks the level's only one
+	for _, e := range w.Enemies() {
+		if !slices.Equal(e.branches, []int{0}) || !slices.Equal(e.route, l.Branches[0].Path) {
+			t.Errorf("enemy %d walks branches %v along %v, want [0] along the path", e.ID, e.branches, e.route)
+		}
+	}
+
+	stepTicks(w, 5*TicksPerSecond)
+	data := saveBytes(t, w)
+	if got := saveBytes(t, restore(t, data, loadTestLevel(t, "meadow"))); !bytes.Equal(got, data) {
+		t.Error("migrated game doesn't save and restore as it is")
+	}
+}
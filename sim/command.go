@@ -0,0 +1,67 @@
+package sim
+
+// Enum of the commands a player can issue
+type CommandKind string
+
+const (
+	CmdCallNextWave CommandKind = "callNextWave"
+	CmdBuild        CommandKind = "build"
+	CmdUpgrade      CommandKind = "upgrade"
+	CmdSell         CommandKind = "sell"
+	CmdSetTargeting CommandKind = "setTargeting"
+	CmdMoveHero     CommandKind = "moveHero"
+	CmdUseAbility   CommandKind = "useAbility"
+)
+
+type Command struct {
+	Kind CommandKind `json:"kind"`
+	// Type id of the tower to build
+	Tower string `json:"tower,omitempty"`
+	// Where to build
+	Cell Cell `json:"cell"`
+	// Tower to upgrade, sell or retarget
+	TowerID   int       `json:"towerId,omitempty"`
+	Targeting Targeting `json:"targeting,omitempty"`
+	// Direction the hero walks in until told otherwise, at most 1 long
+	Move *Vec2 `json:"move,omitempty"`
+	// Id of the hero ability to use
+	Ability string `json:"ability,omitempty"`
+}
+
+// A command with the tick it was applied on
+type TimedCommand struct {
+	Tick int `json:"tick"`
+	Command
+}
+
+// Queue a command, it is applied at the start of the next tick
+func (w *World) Submit(c Command) {
+	w.commands = append(w.commands, c)
+}
+
+func (w *World) applyCommands() {
+	for _, c := range w.commands {
+		if w.historyComplete {
+			w.history = append(w.history, TimedCommand{Tick: w.tick, Command: c})
+		}
+		switch c.Kind {
+		case CmdCallNextWave:
+			w.callNextWave()
+		case CmdBuild:
+			w.build(c.Tower, c.Cell)
+		case CmdUpgrade:
+			w.upgrade(c.TowerID)
+		case CmdSell:
+			w.sell(c.TowerID)
+		case CmdSetTargeting:
+			w.setTargeting(c.TowerID, c.Targeting)
+		case CmdMoveHero:
+			if c.Move != nil {
+				w.moveHero(*c.Move)
+			}
+		case CmdUseAbility:
+			w.useAbility(c.Ability)
+		}
+	}
+	w.commands = w.commands[:0]
+}
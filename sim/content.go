@@ -0,0 +1,119 @@
+package sim
+
+import "bytes"
+
+// Enum of special properties an enemy type can have
+type Trait string
+
+const (
+	// Flies straight to the exit instead of following the road
+	TraitFlying Trait = "flying"
+	// Takes less damage from every hit
+	TraitArmored Trait = "armored"
+	TraitBoss    Trait = "boss"
+)
+
+type EnemyType struct {
+	ID   string
+	Name string
+	HP   float64
+	// Tiles / second
+	Speed float64
+	// Gold awarded for killing it
+	Bounty int
+	// Lives lost when it reaches the exit
+	Damage int
+	// Flat reduction applied to each hit
+	Armor  float64
+	Flying bool
+	Boss   bool
+}
+
+func (t *EnemyType) Traits() []Trait {
+	var traits []Trait
+	if t.Flying {
+		traits = append(traits, TraitFlying)
+	}
+	if t.Armor > 0 {
+		traits = append(traits, TraitArmored)
+	}
+	if t.Boss {
+		traits = append(traits, TraitBoss)
+	}
+	return traits
+}
+
+// Registry of the types levels refer to by id
+type Content struct {
+	Enemies map[string]*EnemyType
+	Towers  map[string]*TowerType
+	// Enemy type ids from the first met to the last
+	EnemyOrder []string
+	// Tower type ids in the order they appear in the build menu
+	TowerOrder []string
+	Endless    EndlessConfig
+	// Difficulties and modifiers by id
+	Modifiers map[string]*Modifier
+	// Difficulty ids from easiest to hardest
+	Difficulties []string
+	// Ids of the modifiers that aren't difficulties, in the order they are listed
+	ModifierOrder []string
+	// The unit the player walks around the map, nil for games without one
+	Hero *HeroType
+}
+
+func DefaultContent() *Content {
+	c := &Content{
+		Enemies:   map[string]*EnemyType{},
+		Towers:    map[string]*TowerType{},
+		Modifiers: map[string]*Modifier{},
+	}
+	for _, e := range []*EnemyType{
+		{ID: "grunt", Name: "Grunt", HP: 30, Speed: 1.5, Bounty: 5, Damage: 1},
+		{ID: "runner", Name: "Runner", HP: 18, Speed: 2.6, Bounty: 4, Damage: 1},
+		{ID: "brute", Name: "Brute", HP: 120, Speed: 0.9, Bounty: 15, Damage: 3},
+		{ID: "bat", Name: "Bat", HP: 22, Speed: 2.2, Bounty: 6, Damage: 1, Flying: true},
+		{ID: "knight", Name: "Knight", HP: 70, Speed: 1.1, Bounty: 10, Damage: 2, Armor: 4},
+		{ID: "ogre", Name: "Ogre Chief", HP: 900, Speed: 0.6, Bounty: 100, Damage: 10, Armor: 2, Boss: true},
+	} {
+		c.Enemies[e.ID] = e
+		c.EnemyOrder = append(c.EnemyOrder, e.ID)
+	}
+	for _, t := range []*TowerType{
+		{ID: "arrow", Name: "Arrow Tower", Cost: 50, Range: 3, Damage: 8, FireRate: 1.5, DamageType: DamagePhysical, ProjectileSpeed: 12, HitsAir: true, CritChance: 0.15, CritMultiplier: 2},
+		{ID: "cannon", Name: "Cannon", Cost: 80, Range: 2.5, Damage: 24, FireRate: 0.6, DamageType: DamagePhysical, ProjectileSpeed: 7, Splash: 1},
+		{ID: "mage", Name: "Mage Tower", Cost: 100, Range: 3, Damage: 14, FireRate: 0.8, DamageType: DamageMagic, ProjectileSpeed: 9, HitsAir: true, CritChance: 0.1, CritMultiplier: 2.5},
+		{ID: "frost", Name: "Frost Tower", Cost: 70, Range: 2.5, Damage: 3, FireRate: 1, DamageType: DamageFrost, ProjectileSpeed: 9, HitsAir: true, Slow: 0.4, SlowDuration: 2},
+	} {
+		c.Towers[t.ID] = t
+		c.TowerOrder = append(c.TowerOrder, t.ID)
+	}
+	c.Hero = &HeroType{
+		ID: "hero", Name: "Warden", HP: 200, Speed: 2.5, Damage: 12, AttackRate: 1.2, Range: 1.2, Radius: 0.3,
+		ContactDamage: 6, Growth: 0.15, XPPerLevel: 30, MaxLevel: 10, RespawnTime: 12,
+		Abilities: []*HeroAbility{
+			{ID: "cleave", Name: "Cleave", Cooldown: 8, Radius: 1.5, Damage: 35, DamageType: DamagePhysical},
+			{ID: "frostNova", Name: "Frost Nova", Cooldown: 15, Radius: 2.5, Damage: 10, DamageType: DamageFrost, Slow: 0.5, SlowDuration: 3},
+			{ID: "secondWind", Name: "Second Wind", Cooldown: 25, Heal: 0.5},
+		},
+	}
+	c.Endless = EndlessConfig{
+		StartBudget: 1.15,
+		Growth:      1.12,
+		BossEvery:   5,
+		MaxGroups:   3,
+		Weights: map[string]float64{
+			"grunt":  4,
+			"runner": 3,
+			"bat":    2,
+			"knight": 2,
+			"brute":  1,
+			"ogre":   1,
+		},
+	}
+	if err := c.LoadModifiers(bytes.NewReader(modifiersJSON)); err != nil {
+		// Embedded with the package, so only a broken build gets here
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,53 @@
+package sim
+
+import (
+	"hash/fnv"
+	"slices"
+	"time"
+)
+
+// Modifiers every daily challenge is played with
+const dailyModifiers = 2
+
+// A level with a seed and modifiers picked from a date, so everyone playing on
+// the same day gets the same game without asking a server
+type Challenge struct {
+	// Day in YYYY-MM-DD form
+	Date      string   `json:"date"`
+	Level     string   `json:"level"`
+	Seed      uint64   `json:"seed"`
+	Modifiers []string `json:"modifiers"`
+}
+
+// The challenge of the day t falls on in its own location. Levels are ids
+// to pick from and should be in the same order for everyone.
+func DailyChallenge(t time.Time, levels []string, content *Content) Challenge {
+	date := t.Format(time.DateOnly)
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	// FNV of dates a day apart differ in few bits, mix them up once more
+	mix := NewRNG(h.Sum64())
+	seed := mix.Uint64()
+	rng := NewRNG(seed)
+
+	ch := Challenge{Date: date, Seed: seed}
+	if len(levels) > 0 {
+		ch.Level = levels[rng.Intn(len(levels))]
+	}
+	// Played on the normal difficulty, so only modifiers are picked
+	pool := slices.Clone(content.ModifierOrder)
+	for range min(dailyModifiers, len(pool)) {
+		i := rng.Intn(len(pool))
+		ch.Modifiers = append(ch.Modifiers, pool[i])
+		pool = slices.Delete(pool, i, i+1)
+	}
+	slices.Sort(ch.Modifiers)
+	return ch
+}
+
+// Copies of the challenge's level and content, seeded and with its modifiers applied
+func (ch Challenge) Apply(level *Level, content *Content) (*Level, *Content, error) {
+	seeded := *level
+	seeded.Seed = ch.Seed
+	return ApplyModifiers(&seeded, content, ch.Modifiers)
+}
@@ -0,0 +1,127 @@
+package sim
+
+import (
+	"math"
+	"slices"
+)
+
+// Tuning of the waves generated in endless mode
+type EndlessConfig struct {
+	// Bounty worth of the first generated wave, as a multiple of the level's last wave
+	StartBudget float64
+	// Multiplier of the budget from one generated wave to the next
+	Growth float64
+	// Every this many generated waves brings a boss on top of its budget, 0 for never
+	BossEvery int
+	// Most enemy types mixed in one wave
+	MaxGroups int
+	// How likely each enemy type is to be picked. Bosses are only picked for
+	// boss waves and types without a weight never show up.
+	Weights map[string]float64
+}
+
+// Budget of a level without waves of its own
+const defaultEndlessBudget = 40
+
+// Whether wave i exists, in endless mode they all do
+func (w *World) hasWave(i int) bool {
+	return w.endless || i < len(w.level.Waves)
+}
+
+// The level's own waves first, then generated ones
+func (w *World) wave(i int) Wave {
+	if i < len(w.level.Waves) {
+		return w.level.Waves[i]
+	}
+	for len(w.level.Waves)+len(w.generated) <= i {
+		w.generated = append(w.generated, generateWave(w.level, w.content, len(w.level.Waves)+len(w.generated)))
+	}
+	return w.generated[i-len(w.level.Waves)]
+}
+
+// Total bounty of the enemies in a wave, used as a measure of its strength
+func waveBudget(content *Content, wave Wave) float64 {
+	budget := 0.0
+	for _, g := range wave.Groups {
+		if t, ok := content.Enemies[g.Enemy]; ok {
+			budget += float64(t.Bounty * g.Count)
+		}
+	}
+	return budget
+}
+
+// Pick an index with probability proportional to its weight
+func pickWeighted(rng *RNG, weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	r := rng.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(weights) - 1
+}
+
+// Wave index of the level, past its own waves. It only depends on the level
+// seed and the index, so the same endless game always gets the same waves.
+func generateWave(level *Level, content *Content, index int) Wave {
+	cfg := content.Endless
+	n := index - len(level.Waves)
+	rng := NewRNG(level.Seed ^ uint64(index+1)*0x9e3779b97f4a7c15)
+
+	base := float64(defaultEndlessBudget)
+	if len(level.Waves) > 0 {
+		base = waveBudget(content, level.Waves[len(level.Waves)-1])
+	}
+	budget := base * cfg.StartBudget * math.Pow(cfg.Growth, float64(n))
+
+	// Sorted so map order doesn't change the waves
+	var regular, bosses []string
+	for id, t := range content.Enemies {
+		if cfg.Weights[id] <= 0 {
+			continue
+		}
+		if t.Boss {
+			bosses = append(bosses, id)
+		} else {
+			regular = append(regular, id)
+		}
+	}
+	slices.Sort(regular)
+	slices.Sort(bosses)
+
+	var wave Wave
+	delay := 0.0
+	groups := min(len(regular), 1+rng.Intn(max(1, cfg.MaxGroups)))
+	for i := range groups {
+		weights := make([]float64, len(regular))
+		for j, id := range regular {
+			weights[j] = cfg.Weights[id]
+		}
+		id := regular[pickWeighted(&rng, weights)]
+		regular = slices.Delete(regular, slices.Index(regular, id), slices.Index(regular, id)+1)
+
+		t := content.Enemies[id]
+		count := max(1, int(budget/float64(groups)/float64(max(1, t.Bounty))))
+		interval := max(0.25, min(1.2, 12/float64(count)))
+		// Groups take turns at the spawns
+		spawn := i % max(1, len(level.Spawns()))
+		wave.Groups = append(wave.Groups, WaveGroup{Enemy: id, Count: count, Delay: delay, Interval: interval, Spawn: spawn})
+		delay += float64(count)*interval*0.5 + float64(i+1)
+	}
+
+	if cfg.BossEvery > 0 && (n+1)%cfg.BossEvery == 0 && len(bosses) > 0 {
+		weights := make([]float64, len(bosses))
+		for j, id := range bosses {
+			weights[j] = cfg.Weights[id]
+		}
+		// One more boss every other boss wave
+		count := 1 + (n+1)/(cfg.BossEvery*2)
+		wave.Groups = append(wave.Groups, WaveGroup{Enemy: bosses[pickWeighted(&rng, weights)], Count: count, Delay: delay + 4, Interval: 6})
+	}
+	return wave
+}
@@ -0,0 +1,153 @@
+package sim
+
+type Enemy struct {
+	ID   int
+	Type *EnemyType
+	HP   float64
+	// Distance walked along the route in tiles
+	Distance float64
+	Pos      Vec2
+	// Direction of the last move, zero before the first one
+	Heading Vec2
+	// Status effects currently applied, at most one per kind
+	Effects []Effect
+
+	// Indices of the branches picked at spawn, and the path along them
+	branches []int
+	route    Path
+}
+
+// Enum of status effects towers can apply to enemies
+type EffectKind string
+
+const (
+	// Reduces speed by Strength as a fraction
+	EffectSlow EffectKind = "slow"
+)
+
+type Effect struct {
+	Kind     EffectKind `json:"kind"`
+	Strength float64    `json:"strength"`
+	// Ticks until the effect wears off
+	Remaining int `json:"remaining"`
+}
+
+// Apply an effect, a stronger or longer one replaces the current one of the same kind
+func (e *Enemy) addEffect(effect Effect) {
+	for i, current := range e.Effects {
+		if current.Kind == effect.Kind {
+			e.Effects[i].Strength = max(current.Strength, effect.Strength)
+			e.Effects[i].Remaining = max(current.Remaining, effect.Remaining)
+			return
+		}
+	}
+	e.Effects = append(e.Effects, effect)
+}
+
+// Tiles / second after effects
+func (e *Enemy) Speed() float64 {
+	speed := e.Type.Speed
+	for _, effect := range e.Effects {
+		if effect.Kind == EffectSlow {
+			speed *= 1 - effect.Strength
+		}
+	}
+	return speed
+}
+
+func (e *Enemy) updateEffects() {
+	active := e.Effects[:0]
+	for _, effect := range e.Effects {
+		effect.Remaining--
+		if effect.Remaining > 0 {
+			active = append(active, effect)
+		}
+	}
+	e.Effects = active
+}
+
+// Spawn an enemy at one of the level's spawns. Its way to an exit is picked
+// right away, with the world's random numbers where the road forks so replays
+// pick the same.
+func (w *World) spawnEnemy(typeID string, spawn int) {
+	t, ok := w.content.Enemies[typeID]
+	if !ok {
+		return
+	}
+	branches := w.level.route(spawn, func(weights []float64) int {
+		return pickWeighted(&w.rng, weights)
+	})
+	route, err := w.level.routePath(branches)
+	if err != nil {
+		return
+	}
+	w.nextID++
+	e := &Enemy{
+		ID:       w.nextID,
+		Type:     t,
+		HP:       t.HP,
+		branches: branches,
+	}
+	e.setRoute(route)
+	e.Pos = e.route.PointAt(0)
+	w.enemies = append(w.enemies, e)
+}
+
+// Flying enemies skip the road and head straight for the exit of their route
+func (e *Enemy) setRoute(p Path) {
+	if e.Type.Flying && len(p) > 2 {
+		p = Path{p[0], p[len(p)-1]}
+	}
+	e.route = p
+}
+
+// Walk enemies along their route, removing the ones that reach an exit
+func (w *World) moveEnemies() {
+	alive := w.enemies[:0]
+	for _, e := range w.enemies {
+		path := e.route
+		e.Distance += e.Speed() * TickDuration
+		e.updateEffects()
+		if e.Distance >= path.Length() {
+			w.lives = max(0, w.lives-e.Type.Damage)
+			w.leaked++
+			w.emit(Event{Kind: EventLeak, ID: e.ID, Type: e.Type.ID, Pos: e.Pos, Amount: float64(e.Type.Damage)})
+			continue
+		}
+		pos := path.PointAt(e.Distance)
+		if d := pos.Sub(e.Pos); d.Len() > 0 {
+			e.Heading = d.Mul(1 / d.Len())
+		}
+		e.Pos = pos
+		alive = append(alive, e)
+	}
+	// Don't keep dangling pointers in the unused tail
+	for i := len(alive); i < len(w.enemies); i++ {
+		w.enemies[i] = nil
+	}
+	w.enemies = alive
+}
+
+// Enemy with the given id, nil if it is dead or gone
+func (w *World) Enemy(id int) *Enemy {
+	for _, e := range w.enemies {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// Drop the enemies killed this tick
+func (w *World) removeDead() {
+	alive := w.enemies[:0]
+	for _, e := range w.enemies {
+		if e.HP > 0 {
+			alive = append(alive, e)
+		}
+	}
+	for i := len(alive); i < len(w.enemies); i++ {
+		w.enemies[i] = nil
+	}
+	w.enemies = alive
+}
@@ -0,0 +1,51 @@
+package sim
+
+// Enum of things that happened during a tick which the frontend may want to show
+type EventKind string
+
+const (
+	// An enemy took Amount damage
+	EventDamage EventKind = "damage"
+	// Amount gold was gained
+	EventGold EventKind = "gold"
+	// A tower fired at Pos
+	EventFire EventKind = "fire"
+	// An enemy was killed
+	EventDeath EventKind = "death"
+	// A projectile landed at Pos
+	EventImpact EventKind = "impact"
+	// A tower was built, or upgraded to level Amount
+	EventBuild EventKind = "build"
+	// An enemy reached the exit and cost Amount lives
+	EventLeak EventKind = "leak"
+	// The hero attacked the enemy at Pos
+	EventHeroAttack EventKind = "heroAttack"
+	// The hero used the ability with id Type, reaching Amount tiles around Pos
+	EventHeroAbility EventKind = "heroAbility"
+	// The hero reached level Amount
+	EventHeroLevel   EventKind = "heroLevel"
+	EventHeroDeath   EventKind = "heroDeath"
+	EventHeroRespawn EventKind = "heroRespawn"
+)
+
+type Event struct {
+	Kind EventKind
+	// Tower or enemy the event is about
+	ID int
+	// Type id of that tower or enemy
+	Type string
+	// Where it happened, in tiles
+	Pos    Vec2
+	Amount float64
+	// Set on damage events from critical hits
+	Crit bool
+}
+
+func (w *World) emit(e Event) {
+	w.events = append(w.events, e)
+}
+
+// Events of the last tick, the slice is reused by the next one
+func (w *World) Events() []Event {
+	return w.events
+}
@@ -0,0 +1,303 @@
+package sim
+
+import "math"
+
+// An active ability of the hero, used on a hotkey. It hits every enemy within
+// Radius of the hero and can heal the hero as well.
+type HeroAbility struct {
+	ID   string
+	Name string
+	// Seconds before it can be used again
+	Cooldown float64
+	Radius   float64
+	// Dealt to each enemy in the radius, it grows with the hero's level like its attacks do
+	Damage     float64
+	DamageType DamageType
+	// Fraction of speed taken from the enemies hit, and for how many seconds
+	Slow         float64
+	SlowDuration float64
+	// Fraction of the hero's max HP restored
+	Heal float64
+}
+
+// Stats of the hero at level 1
+type HeroType struct {
+	ID   string
+	Name string
+	HP   float64
+	// Tiles / second
+	Speed float64
+	// Physical damage of each attack
+	Damage float64
+	// Attacks / second
+	AttackRate float64
+	// Reach of the attacks in tiles
+	Range float64
+	// Half the width of its body in tiles, which can't overlap tiles it can't walk on
+	Radius float64
+	// HP / second lost to each enemy touching it, per life the enemy would cost
+	ContactDamage float64
+	// Added to HP and damage each level, as a fraction of the level 1 values
+	Growth float64
+	// XP needed for level 2, each level after needs this much more than the one before
+	XPPerLevel int
+	MaxLevel   int
+	// Seconds before it comes back after dying
+	RespawnTime float64
+	Abilities   []*HeroAbility
+}
+
+// Enemies closer than this to the hero's body touch it
+const enemyReach = 0.3
+
+// Tiles closer than this to the road are on it, the same reach the map
+// generator draws roads with
+const roadReach = 0.75
+
+// The unit the player walks around the map. It attacks enemies near it on
+// its own, uses abilities when told to and comes back home a while after dying.
+type Hero struct {
+	Type *HeroType
+	Pos  Vec2
+	// Direction it was told to walk in, at most 1 long, zero to stand still
+	Move Vec2
+	// Direction of the last move, zero before the first one
+	Heading Vec2
+	HP      float64
+	Level   int
+	// Gained since reaching the current level
+	XP    int
+	Kills int
+	// Ticks until it comes back, counted while it is dead
+	respawn        int
+	attackCooldown int
+	// Ticks until each ability can be used again, in the order of Type.Abilities
+	cooldowns []int
+}
+
+func newHero(t *HeroType, pos Vec2) *Hero {
+	h := &Hero{Type: t, Pos: pos, Level: 1, cooldowns: make([]int, len(t.Abilities))}
+	h.HP = h.MaxHP()
+	return h
+}
+
+func (h *Hero) growth() float64 {
+	return 1 + h.Type.Growth*float64(h.Level-1)
+}
+
+func (h *Hero) MaxHP() float64 {
+	return h.Type.HP * h.growth()
+}
+
+func (h *Hero) Damage() float64 {
+	return h.Type.Damage * h.growth()
+}
+
+func (h *Hero) Alive() bool {
+	return h.HP > 0
+}
+
+// XP needed for the next level, false at max level
+func (h *Hero) XPToLevel() (int, bool) {
+	if h.Level >= h.Type.MaxLevel {
+		return 0, false
+	}
+	return h.Type.XPPerLevel * h.Level, true
+}
+
+// Seconds until ability i can be used again, 0 when it is ready
+func (h *Hero) Cooldown(i int) float64 {
+	if i < 0 || i >= len(h.cooldowns) {
+		return 0
+	}
+	return float64(h.cooldowns[i]) * TickDuration
+}
+
+// Seconds until a dead hero comes back
+func (h *Hero) RespawnIn() float64 {
+	return float64(h.respawn) * TickDuration
+}
+
+// The hero, nil in games played without one
+func (w *World) Hero() *Hero {
+	return w.hero
+}
+
+// Tiles the hero can walk on: the road and plain ground, but not houses,
+// fences or anything else placed on the layers above the ground
+func walkableTiles(l *Level) []bool {
+	walkable := make([]bool, l.Width*l.Height)
+	for i := range walkable {
+		c := Cell{X: i % l.Width, Y: i / l.Width}
+		walkable[i] = true
+		for _, layer := range l.Layers[1:] {
+			if layer[i] != 0 {
+				walkable[i] = false
+			}
+		}
+		if l.DistanceToPath(c.Center()) < roadReach {
+			walkable[i] = true
+		}
+	}
+	return walkable
+}
+
+// Where the hero starts and comes back: the walkable tile closest to the
+// first exit, which is what the enemies are after
+func (w *World) findHeroHome() Vec2 {
+	target := Vec2{X: float64(w.level.Width) / 2, Y: float64(w.level.Height) / 2}
+	if exits := w.level.Exits(); len(exits) > 0 {
+		target = exits[0]
+	}
+	home, best := target, math.Inf(1)
+	for i, ok := range w.walkable {
+		c := Cell{X: i % w.level.Width, Y: i / w.level.Width}
+		if d := c.Center().Sub(target).Len(); ok && d < best {
+			home, best = c.Center(), d
+		}
+	}
+	return home
+}
+
+// Whether the hero's body fits at p without overlapping a tile it can't walk on
+func (w *World) heroFits(p Vec2) bool {
+	r := w.hero.Type.Radius
+	for y := int(math.Floor(p.Y - r)); y <= int(math.Ceil(p.Y+r))-1; y++ {
+		for x := int(math.Floor(p.X - r)); x <= int(math.Ceil(p.X+r))-1; x++ {
+			c := Cell{X: x, Y: y}
+			if !w.level.Contains(c) || !w.walkable[y*w.level.Width+x] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (w *World) moveHero(dir Vec2) {
+	if w.hero == nil {
+		return
+	}
+	if l := dir.Len(); l > 1 {
+		dir = dir.Mul(1 / l)
+	}
+	w.hero.Move = dir
+}
+
+func (w *World) useAbility(id string) {
+	h := w.hero
+	if h == nil || !h.Alive() {
+		return
+	}
+	for i, a := range h.Type.Abilities {
+		if a.ID != id || h.cooldowns[i] > 0 {
+			continue
+		}
+		h.cooldowns[i] = secondsToTicks(a.Cooldown)
+		w.emit(Event{Kind: EventHeroAbility, Type: a.ID, Pos: h.Pos, Amount: a.Radius})
+		for _, e := range w.enemies {
+			if e.HP <= 0 || e.Pos.Sub(h.Pos).Len() > a.Radius {
+				continue
+			}
+			if a.Damage > 0 {
+				w.heroHit(e, a.Damage*h.growth(), a.DamageType)
+			}
+			if a.Slow > 0 && e.HP > 0 {
+				e.addEffect(Effect{Kind: EffectSlow, Strength: a.Slow, Remaining: secondsToTicks(a.SlowDuration)})
+			}
+		}
+		h.HP = min(h.MaxHP(), h.HP+a.Heal*h.MaxHP())
+		w.removeDead()
+		return
+	}
+}
+
+// Walk, take damage from the enemies touching it, attack and come back after dying
+func (w *World) updateHero() {
+	h := w.hero
+	if h == nil {
+		return
+	}
+	for i := range h.cooldowns {
+		h.cooldowns[i] = max(0, h.cooldowns[i]-1)
+	}
+	if !h.Alive() {
+		if h.respawn--; h.respawn <= 0 {
+			h.Pos = w.heroHome
+			h.HP = h.MaxHP()
+			h.attackCooldown = 0
+			w.emit(Event{Kind: EventHeroRespawn, Pos: h.Pos})
+		}
+		return
+	}
+
+	// One axis at a time, so it slides along whatever it walks into
+	step := h.Move.Mul(h.Type.Speed * TickDuration)
+	from := h.Pos
+	if next := h.Pos.Add(Vec2{X: step.X}); step.X != 0 && w.heroFits(next) {
+		h.Pos = next
+	}
+	if next := h.Pos.Add(Vec2{Y: step.Y}); step.Y != 0 && w.heroFits(next) {
+		h.Pos = next
+	}
+	if d := h.Pos.Sub(from); d.Len() > 0 {
+		h.Heading = d.Mul(1 / d.Len())
+	}
+
+	for _, e := range w.enemies {
+		if e.HP > 0 && !e.Type.Flying && e.Pos.Sub(h.Pos).Len() <= h.Type.Radius+enemyReach {
+			h.HP -= h.Type.ContactDamage * float64(e.Type.Damage) * TickDuration
+		}
+	}
+	if h.HP <= 0 {
+		h.HP = 0
+		h.respawn = secondsToTicks(h.Type.RespawnTime)
+		w.emit(Event{Kind: EventHeroDeath, Pos: h.Pos})
+		return
+	}
+
+	if h.attackCooldown > 0 {
+		h.attackCooldown--
+		return
+	}
+	var target *Enemy
+	best := h.Type.Range
+	for _, e := range w.enemies {
+		if d := e.Pos.Sub(h.Pos).Len(); e.HP > 0 && d <= best {
+			target, best = e, d
+		}
+	}
+	if target == nil {
+		return
+	}
+	w.emit(Event{Kind: EventHeroAttack, Pos: target.Pos})
+	w.heroHit(target, h.Damage(), DamagePhysical)
+	h.attackCooldown = secondsToTicks(1 / h.Type.AttackRate)
+	w.removeDead()
+}
+
+// Damage an enemy on behalf of the hero, which gains its bounty as XP for the kill
+func (w *World) heroHit(e *Enemy, damage float64, damageType DamageType) {
+	w.damageEnemy(e, damage, damageType, false)
+	if e.HP > 0 {
+		return
+	}
+	h := w.hero
+	h.Kills++
+	h.XP += e.Type.Bounty
+	for {
+		need, ok := h.XPToLevel()
+		if !ok {
+			h.XP = 0
+			return
+		}
+		if h.XP < need {
+			return
+		}
+		h.XP -= need
+		// Keep the HP missing, so levelling up heals by what the level adds
+		missing := h.MaxHP() - h.HP
+		h.Level++
+		h.HP = h.MaxHP() - missing
+		w.emit(Event{Kind: EventHeroLevel, Pos: h.Pos, Amount: float64(h.Level)})
+	}
+}
@@ -0,0 +1,39 @@
+package sim
+
+// Static description of a map and the waves played on it
+type Level struct {
+	ID   string
+	Name string
+	// Size in tiles
+	Width, Height int
+	// Tile ids per layer, row by row
+	Layers [][]int
+	// Whether towers can go on each tile, row by row
+	Buildable []bool
+	// Roads enemies walk, from the spawns to the exits
+	Branches []Branch
+
+	StartingGold  int
+	StartingLives int
+	Waves         []Wave
+	// Seconds before the first wave starts on its own
+	FirstWaveDelay float64
+	// Seconds from one wave starting to the next one starting on its own
+	WaveInterval float64
+	// Gold per second of countdown skipped by calling a wave early
+	EarlyCallBonus float64
+	// Seeds the random numbers used during play, like critical hits
+	Seed uint64
+	// Ids of the modifiers applied to the level, set by ApplyModifiers
+	Modifiers []string
+	// Towers can't be sold
+	NoSelling bool
+}
+
+func (l *Level) Contains(c Cell) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < l.Width && c.Y < l.Height
+}
+
+func (l *Level) CanBuild(c Cell) bool {
+	return l.Contains(c) && l.Buildable[c.Y*l.Width+c.X]
+}
@@ -0,0 +1,229 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// On disk representation of a Level, the map format shared by the game, the
+// editor and the map generator
+type levelFile struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Tile ids per layer, as rows of columns
+	Layers [][][]int `json:"layers"`
+	// Tiles towers can't go on besides the decorated and road ones
+	Blocked []Cell `json:"blocked,omitempty"`
+	// Tiles towers can go on despite what is placed on them
+	Open []Cell `json:"open,omitempty"`
+	// A road without forks, written instead of branches when that is all there is
+	Path     Path         `json:"path,omitempty"`
+	Branches []branchFile `json:"branches,omitempty"`
+
+	StartingGold   int        `json:"startingGold"`
+	StartingLives  int        `json:"startingLives"`
+	FirstWaveDelay float64    `json:"firstWaveDelay"`
+	WaveInterval   float64    `json:"waveInterval"`
+	EarlyCallBonus float64    `json:"earlyCallBonus"`
+	Seed           uint64     `json:"seed,omitempty"`
+	Waves          []waveFile `json:"waves"`
+}
+
+type branchFile struct {
+	Path   Path    `json:"path"`
+	Weight float64 `json:"weight,omitempty"`
+}
+
+type waveFile struct {
+	Groups []waveGroupFile `json:"groups"`
+}
+
+type waveGroupFile struct {
+	Enemy    string  `json:"enemy"`
+	Count    int     `json:"count"`
+	Delay    float64 `json:"delay,omitempty"`
+	Interval float64 `json:"interval,omitempty"`
+	Spawn    int     `json:"spawn,omitempty"`
+}
+
+// Check that the level can be played: it starts with lives to lose, and its
+// branches pass CheckBranches and stay on the map. LoadLevel checks every level.
+func (l *Level) Check() error {
+	if l.StartingLives <= 0 {
+		return fmt.Errorf("starts with %d lives", l.StartingLives)
+	}
+	if err := l.CheckBranches(); err != nil {
+		return err
+	}
+	for i, b := range l.Branches {
+		for _, p := range b.Path {
+			// Roads may run a tile past the edges, so enemies walk on and off the map
+			if p.X < -1 || p.Y < -1 || p.X > float64(l.Width+1) || p.Y > float64(l.Height+1) {
+				return fmt.Errorf("branch %d goes off the map at %g,%g", i, p.X, p.Y)
+			}
+		}
+	}
+	return nil
+}
+
+func LoadLevel(r io.Reader) (*Level, error) {
+	var f levelFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Width <= 0 || f.Height <= 0 {
+		return nil, fmt.Errorf("level %s: size %dx%d", f.ID, f.Width, f.Height)
+	}
+	if len(f.Layers) == 0 {
+		return nil, fmt.Errorf("level %s: needs a layer", f.ID)
+	}
+	if len(f.Path) > 0 && len(f.Branches) > 0 {
+		return nil, fmt.Errorf("level %s: has both a path and branches", f.ID)
+	}
+	var layers [][]int
+	for i, rows := range f.Layers {
+		if len(rows) != f.Height {
+			return nil, fmt.Errorf("level %s: layer %d has %d rows, want %d", f.ID, i, len(rows), f.Height)
+		}
+		var layer []int
+		for y, row := range rows {
+			if len(row) != f.Width {
+				return nil, fmt.Errorf("level %s: layer %d row %d has %d tiles, want %d", f.ID, i, y, len(row), f.Width)
+			}
+			layer = append(layer, row...)
+		}
+		layers = append(layers, layer)
+	}
+
+	l := &Level{
+		ID:             f.ID,
+		Name:           f.Name,
+		Width:          f.Width,
+		Height:         f.Height,
+		Layers:         layers,
+		StartingGold:   f.StartingGold,
+		StartingLives:  f.StartingLives,
+		FirstWaveDelay: f.FirstWaveDelay,
+		WaveInterval:   f.WaveInterval,
+		EarlyCallBonus: f.EarlyCallBonus,
+		Seed:           f.Seed,
+	}
+	if len(f.Path) > 0 {
+		l.Branches = []Branch{{Path: f.Path}}
+	}
+	for _, b := range f.Branches {
+		l.Branches = append(l.Branches, Branch(b))
+	}
+	if err := l.Check(); err != nil {
+		return nil, fmt.Errorf("level %s: %w", f.ID, err)
+	}
+	spawns := len(l.Spawns())
+	for _, w := range f.Waves {
+		var wave Wave
+		for _, g := range w.Groups {
+			if g.Spawn < 0 || g.Spawn >= spawns {
+				return nil, fmt.Errorf("level %s: wave %d has no spawn %d", f.ID, len(l.Waves)+1, g.Spawn)
+			}
+			wave.Groups = append(wave.Groups, WaveGroup(g))
+		}
+		l.Waves = append(l.Waves, wave)
+	}
+	l.Buildable = BuildableTiles(l)
+	for _, c := range f.Blocked {
+		if l.Contains(c) {
+			l.Buildable[c.Y*l.Width+c.X] = false
+		}
+	}
+	for _, c := range f.Open {
+		if l.Contains(c) {
+			l.Buildable[c.Y*l.Width+c.X] = true
+		}
+	}
+	return l, nil
+}
+
+// Write the level in the format LoadLevel reads
+func SaveLevel(w io.Writer, l *Level) error {
+	f := levelFile{
+		ID:             l.ID,
+		Name:           l.Name,
+		Width:          l.Width,
+		Height:         l.Height,
+		StartingGold:   l.StartingGold,
+		StartingLives:  l.StartingLives,
+		FirstWaveDelay: l.FirstWaveDelay,
+		WaveInterval:   l.WaveInterval,
+		EarlyCallBonus: l.EarlyCallBonus,
+		Seed:           l.Seed,
+	}
+	if len(l.Branches) == 1 && l.Branches[0].Weight == 0 {
+		f.Path = l.Branches[0].Path
+	} else {
+		for _, b := range l.Branches {
+			f.Branches = append(f.Branches, branchFile(b))
+		}
+	}
+	for _, layer := range l.Layers {
+		var rows [][]int
+		for y := range l.Height {
+			rows = append(rows, layer[y*l.Width:(y+1)*l.Width])
+		}
+		f.Layers = append(f.Layers, rows)
+	}
+	derived := BuildableTiles(l)
+	for i, b := range l.Buildable {
+		c := Cell{X: i % l.Width, Y: i / l.Width}
+		switch {
+		case derived[i] && !b:
+			f.Blocked = append(f.Blocked, c)
+		case !derived[i] && b:
+			f.Open = append(f.Open, c)
+		}
+	}
+	for _, wave := range l.Waves {
+		var wf waveFile
+		for _, g := range wave.Groups {
+			wf.Groups = append(wf.Groups, waveGroupFile(g))
+		}
+		f.Waves = append(f.Waves, wf)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	// One line per row of tiles, and per point, cell or wave group
+	data = numberArray.ReplaceAllFunc(data, func(b []byte) []byte {
+		return whitespace.ReplaceAll(b, nil)
+	})
+	data = flatObject.ReplaceAllFunc(data, func(b []byte) []byte {
+		return whitespace.ReplaceAll(b, []byte(" "))
+	})
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+var (
+	numberArray = regexp.MustCompile(`\[[\s\d.,-]*\]`)
+	flatObject  = regexp.MustCompile(`\{[^{}\[\]]*\}`)
+	whitespace  = regexp.MustCompile(`\s+`)
+)
+
+// Towers can go on plain ground, but not on anything placed on the layers
+// above it like houses and roads, nor on tiles a branch of the road crosses
+func BuildableTiles(l *Level) []bool {
+	buildable := make([]bool, l.Width*l.Height)
+	for i := range buildable {
+		c := Cell{X: i % l.Width, Y: i / l.Width}
+		buildable[i] = l.DistanceToPath(c.Center()) > 0.5
+		for _, layer := range l.Layers[1:] {
+			if layer[i] != 0 {
+				buildable[i] = false
+			}
+		}
+	}
+	return buildable
+}
@@ -0,0 +1,46 @@
+package sim
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadLevelRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(l *Level)
+		// Part of the error, empty when the level loads
+		err string
+	}{
+		{"unchanged", func(l *Level) {}, ""},
+		{"no lives", func(l *Level) { l.StartingLives = 0 }, "starts with 0 lives"},
+		{"negative lives", func(l *Level) { l.StartingLives = -3 }, "starts with -3 lives"},
+		{"spawn a tile below the map", func(l *Level) { l.Branches[0].Path[0].Y = float64(l.Height + 1) }, ""},
+		{"spawn further below the map", func(l *Level) { l.Branches[0].Path[0].Y = float64(l.Height + 2) }, "off the map"},
+		{"turn left of the map", func(l *Level) {
+			p := l.Branches[0].Path
+			l.Branches[0].Path = slices.Insert(slices.Clone(p), 1, Vec2{X: -3, Y: p[0].Y}, Vec2{X: -3, Y: p[1].Y})
+		}, "off the map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := loadTestLevel(t, "meadow")
+			tt.change(l)
+			var buf bytes.Buffer
+			if err := SaveLevel(&buf, l); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadLevel(&buf)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case tt.err != "" && err == nil:
+				t.Fatalf("loaded, want an error about %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("error %q, want one about %q", err, tt.err)
+			}
+		})
+	}
+}
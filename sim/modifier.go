@@ -0,0 +1,197 @@
+package sim
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+//go:embed modifiers.json
+var modifiersJSON []byte
+
+// The difficulty a level is played on when none is picked
+const DefaultDifficulty = "normal"
+
+// A change to the rules a level is played with, either a difficulty or a
+// modifier that can be combined with others. Multipliers of 0 leave the
+// value as it is.
+type Modifier struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	EnemyHP      float64 `json:"enemyHP,omitempty"`
+	EnemySpeed   float64 `json:"enemySpeed,omitempty"`
+	StartingGold float64 `json:"startingGold,omitempty"`
+	// Gold from bounties and calling waves early
+	Income    float64 `json:"income,omitempty"`
+	TowerCost float64 `json:"towerCost,omitempty"`
+	Lives     float64 `json:"lives,omitempty"`
+	// Number of tower types left to build, picked with the level seed. 0 keeps them all.
+	TowerTypes int  `json:"towerTypes,omitempty"`
+	NoSelling  bool `json:"noSelling,omitempty"`
+	// Added to the score multiplier of games played with the modifier
+	ScoreBonus float64 `json:"scoreBonus,omitempty"`
+}
+
+// On disk list of the difficulties and modifiers
+type modifiersFile struct {
+	Difficulties []*Modifier `json:"difficulties"`
+	Modifiers    []*Modifier `json:"modifiers"`
+}
+
+// Register the difficulties and modifiers of a modifiers file
+func (c *Content) LoadModifiers(r io.Reader) error {
+	var f modifiersFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return err
+	}
+	add := func(m *Modifier) error {
+		if m.ID == "" {
+			return errors.New("modifier without an id")
+		}
+		if _, ok := c.Modifiers[m.ID]; ok {
+			return fmt.Errorf("modifier %s is defined twice", m.ID)
+		}
+		for _, v := range []float64{m.EnemyHP, m.EnemySpeed, m.StartingGold, m.Income, m.TowerCost, m.Lives} {
+			if v < 0 {
+				return fmt.Errorf("modifier %s has a negative multiplier", m.ID)
+			}
+		}
+		c.Modifiers[m.ID] = m
+		return nil
+	}
+	for _, m := range f.Difficulties {
+		if err := add(m); err != nil {
+			return err
+		}
+		c.Difficulties = append(c.Difficulties, m.ID)
+	}
+	for _, m := range f.Modifiers {
+		if err := add(m); err != nil {
+			return err
+		}
+		c.ModifierOrder = append(c.ModifierOrder, m.ID)
+	}
+	return nil
+}
+
+// Copies of level and content with the modifiers applied in order. The level
+// remembers them so saves and replays can apply them again.
+func ApplyModifiers(level *Level, content *Content, ids []string) (*Level, *Content, error) {
+	l := *level
+	l.Modifiers = append([]string(nil), ids...)
+	c := content.clone()
+	for _, id := range ids {
+		m, ok := content.Modifiers[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown modifier %q", id)
+		}
+		m.apply(&l, c)
+	}
+	return &l, c, nil
+}
+
+func scale(v *float64, by float64) {
+	if by != 0 {
+		*v *= by
+	}
+}
+
+// Scale an amount of gold or lives, keeping at least 1 of it
+func scaleInt(v *int, by float64) {
+	if by != 0 {
+		*v = max(1, int(math.Round(float64(*v)*by)))
+	}
+}
+
+func (m *Modifier) apply(l *Level, c *Content) {
+	for _, t := range c.Enemies {
+		scale(&t.HP, m.EnemyHP)
+		scale(&t.Speed, m.EnemySpeed)
+		scaleInt(&t.Bounty, m.Income)
+	}
+	for _, t := range c.Towers {
+		scaleInt(&t.Cost, m.TowerCost)
+	}
+	scaleInt(&l.StartingGold, m.StartingGold)
+	scaleInt(&l.StartingLives, m.Lives)
+	scale(&l.EarlyCallBonus, m.Income)
+	if m.NoSelling {
+		l.NoSelling = true
+	}
+	if m.TowerTypes > 0 && m.TowerTypes < len(c.TowerOrder) {
+		// Keep the build menu order of the types left
+		rng := NewRNG(l.Seed)
+		pool := slices.Clone(c.TowerOrder)
+		var keep []string
+		for range m.TowerTypes {
+			i := rng.Intn(len(pool))
+			keep = append(keep, pool[i])
+			pool = slices.Delete(pool, i, i+1)
+		}
+		order := c.TowerOrder[:0]
+		for _, id := range c.TowerOrder {
+			if slices.Contains(keep, id) {
+				order = append(order, id)
+			} else {
+				delete(c.Towers, id)
+			}
+		}
+		c.TowerOrder = order
+	}
+}
+
+// Deep enough copy of the registry for modifiers to change the types in it
+func (c *Content) clone() *Content {
+	n := *c
+	n.Enemies = make(map[string]*EnemyType, len(c.Enemies))
+	for id, t := range c.Enemies {
+		copied := *t
+		n.Enemies[id] = &copied
+	}
+	n.Towers = make(map[string]*TowerType, len(c.Towers))
+	for id, t := range c.Towers {
+		copied := *t
+		n.Towers[id] = &copied
+	}
+	n.TowerOrder = slices.Clone(c.TowerOrder)
+	return &n
+}
+
+// Points for the result of a game, split by where they came from
+type ScoreBreakdown struct {
+	Waves      int     `json:"waves"`
+	Lives      int     `json:"lives"`
+	Gold       int     `json:"gold"`
+	Multiplier float64 `json:"multiplier"`
+	Total      int     `json:"total"`
+}
+
+// Points per wave reached and per life kept
+const (
+	waveScore = 50
+	lifeScore = 100
+)
+
+// Score of the game so far, with a bonus for each modifier played with. Unlike
+// Score it also rewards lost games for the waves they got through.
+func (w *World) ScoreBreakdown() ScoreBreakdown {
+	b := ScoreBreakdown{
+		Waves:      w.WavesStarted() * waveScore,
+		Lives:      max(0, w.lives) * lifeScore,
+		Gold:       w.gold,
+		Multiplier: 1,
+	}
+	for _, id := range w.level.Modifiers {
+		if m, ok := w.content.Modifiers[id]; ok {
+			b.Multiplier += m.ScoreBonus
+		}
+	}
+	b.Total = int(float64(b.Waves+b.Lives+b.Gold) * b.Multiplier)
+	return b
+}
@@ -0,0 +1,52 @@
+package sim
+
+import "math"
+
+// Waypoints enemies walk along, from the first to the last
+type Path []Vec2
+
+// Total walking distance in tiles
+func (p Path) Length() float64 {
+	var l float64
+	for i := 1; i < len(p); i++ {
+		l += p[i].Sub(p[i-1]).Len()
+	}
+	return l
+}
+
+// Point the given distance along the path, clamped to its ends
+func (p Path) PointAt(d float64) Vec2 {
+	if len(p) == 0 {
+		return Vec2{}
+	}
+	for i := 1; i < len(p); i++ {
+		seg := p[i].Sub(p[i-1]).Len()
+		if d <= seg {
+			if seg == 0 {
+				return p[i]
+			}
+			return p[i-1].Lerp(p[i], d/seg)
+		}
+		d -= seg
+	}
+	return p[len(p)-1]
+}
+
+// Shortest distance from v to any point on the path
+func (p Path) DistanceTo(v Vec2) float64 {
+	if len(p) == 0 {
+		return math.Inf(1)
+	}
+	best := p[0].Sub(v).Len()
+	for i := 1; i < len(p); i++ {
+		seg := p[i].Sub(p[i-1])
+		l2 := seg.X*seg.X + seg.Y*seg.Y
+		t := 0.0
+		if l2 > 0 {
+			t = ((v.X-p[i-1].X)*seg.X + (v.Y-p[i-1].Y)*seg.Y) / l2
+			t = max(0, min(1, t))
+		}
+		best = min(best, p[i-1].Lerp(p[i], t).Sub(v).Len())
+	}
+	return best
+}
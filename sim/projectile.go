@@ -0,0 +1,109 @@
+package sim
+
+type Projectile struct {
+	ID  int
+	Pos Vec2
+	// Enemy the projectile is homing in on
+	TargetID int
+	TowerID  int
+	Damage   float64
+	// Tiles / second
+	Speed float64
+	Type  *TowerType
+	Crit  bool
+}
+
+func (w *World) fire(t *Tower, target *Enemy) {
+	damage := t.Damage()
+	// Roll when firing so the outcome doesn't depend on what else gets hit this tick
+	crit := t.Type.CritChance > 0 && w.rng.Float64() < t.Type.CritChance
+	if crit {
+		damage *= t.Type.CritMultiplier
+	}
+	w.emit(Event{Kind: EventFire, ID: t.ID, Type: t.Type.ID, Pos: target.Pos})
+	w.nextID++
+	w.projectiles = append(w.projectiles, &Projectile{
+		ID:       w.nextID,
+		Pos:      t.Cell.Center(),
+		TargetID: target.ID,
+		TowerID:  t.ID,
+		Damage:   damage,
+		Speed:    t.Type.ProjectileSpeed,
+		Type:     t.Type,
+		Crit:     crit,
+	})
+}
+
+// Move projectiles towards their targets and apply the hits
+func (w *World) moveProjectiles() {
+	flying := w.projectiles[:0]
+	for _, p := range w.projectiles {
+		target := w.Enemy(p.TargetID)
+		if target == nil || target.HP <= 0 {
+			// The target died or got away, so the shot fizzles
+			continue
+		}
+		to := target.Pos.Sub(p.Pos)
+		step := p.Speed * TickDuration
+		if to.Len() > step {
+			p.Pos = p.Pos.Add(to.Mul(step / to.Len()))
+			flying = append(flying, p)
+			continue
+		}
+		p.Pos = target.Pos
+		w.impact(p, target)
+	}
+	for i := len(flying); i < len(w.projectiles); i++ {
+		w.projectiles[i] = nil
+	}
+	w.projectiles = flying
+	w.removeDead()
+}
+
+func (w *World) impact(p *Projectile, target *Enemy) {
+	w.emit(Event{Kind: EventImpact, ID: p.TowerID, Type: p.Type.ID, Pos: p.Pos})
+	if p.Type.Splash <= 0 {
+		w.hit(p, target)
+		return
+	}
+	for _, e := range w.enemies {
+		if e.HP <= 0 || (e.Type.Flying && !p.Type.HitsAir) {
+			continue
+		}
+		if e == target || e.Pos.Sub(p.Pos).Len() <= p.Type.Splash {
+			w.hit(p, e)
+		}
+	}
+}
+
+func (w *World) hit(p *Projectile, e *Enemy) {
+	damage := w.damageEnemy(e, p.Damage, p.Type.DamageType, p.Crit)
+	tower := w.Tower(p.TowerID)
+	if tower != nil {
+		tower.DamageDealt += damage
+	}
+	if p.Type.DamageType == DamageFrost {
+		e.addEffect(Effect{Kind: EffectSlow, Strength: p.Type.Slow, Remaining: secondsToTicks(p.Type.SlowDuration)})
+	}
+	if e.HP <= 0 && tower != nil {
+		tower.Kills++
+	}
+}
+
+// Take damage off an enemy, less armor for physical damage, and pay out its
+// bounty if it dies. Returns the damage dealt.
+func (w *World) damageEnemy(e *Enemy, damage float64, damageType DamageType, crit bool) float64 {
+	if damageType == DamagePhysical {
+		// Armor never blocks a hit completely
+		damage = max(damage-e.Type.Armor, damage*0.2)
+	}
+	damage = min(damage, e.HP)
+	e.HP -= damage
+	w.emit(Event{Kind: EventDamage, ID: e.ID, Type: e.Type.ID, Pos: e.Pos, Amount: damage, Crit: crit})
+	if e.HP <= 0 {
+		w.gold += e.Type.Bounty
+		w.emit(Event{Kind: EventDeath, ID: e.ID, Type: e.Type.ID, Pos: e.Pos})
+		w.emit(Event{Kind: EventGold, Pos: e.Pos, Amount: float64(e.Type.Bounty)})
+	}
+	return damage
+}
@@ -0,0 +1,189 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version of the replay format written by WriteReplay. Version 2 games have
+// a hero, older ones are played back without it.
+const ReplayVersion = 2
+
+// Ticks between the snapshots a Playback keeps for seeking
+const snapshotInterval = 10 * TicksPerSecond
+
+// Everything needed to play a game again: the level, its seed and the
+// commands the player gave. The simulation is deterministic, so stepping a
+// new world through the same commands gives the same game.
+type Replay struct {
+	Version int    `json:"version"`
+	Level   string `json:"level"`
+	Seed    uint64 `json:"seed"`
+	Endless bool   `json:"endless,omitempty"`
+	// Applied to the level before playing it
+	Modifiers []string `json:"modifiers,omitempty"`
+	// Ticks played, the game ends or stops being recorded after this many
+	Ticks    int            `json:"ticks"`
+	Commands []TimedCommand `json:"commands"`
+}
+
+// The game played so far as a replay. Fails for worlds restored from saves
+// that didn't keep their command history.
+func (w *World) Replay() (*Replay, error) {
+	if !w.historyComplete {
+		return nil, errors.New("the game was resumed from a save without history")
+	}
+	version := ReplayVersion
+	if w.hero == nil && w.content.Hero != nil {
+		// Carried on from a save made before the hero, so it plays back without one
+		version = 1
+	}
+	return &Replay{
+		Version:   version,
+		Level:     w.level.ID,
+		Seed:      w.level.Seed,
+		Endless:   w.endless,
+		Modifiers: w.level.Modifiers,
+		Ticks:     w.tick,
+		Commands:  append([]TimedCommand(nil), w.history...),
+	}, nil
+}
+
+// Write the replay without indentation to keep long games small
+func WriteReplay(wr io.Writer, r *Replay) error {
+	return json.NewEncoder(wr).Encode(r)
+}
+
+func ReadReplay(r io.Reader) (*Replay, error) {
+	var replay Replay
+	if err := json.NewDecoder(r).Decode(&replay); err != nil {
+		return nil, err
+	}
+	if replay.Version > ReplayVersion {
+		return nil, fmt.Errorf("replay version %d is newer than this game supports (%d)", replay.Version, ReplayVersion)
+	}
+	return &replay, nil
+}
+
+type snapshot struct {
+	tick int
+	// Index of the first command not applied yet
+	next int
+	save []byte
+}
+
+// Plays a replay back tick by tick, and can jump to any tick by restoring
+// the closest earlier snapshot and stepping from there
+type Playback struct {
+	replay *Replay
+	// Level and content before the replay's modifiers, snapshots apply them again
+	level   *Level
+	content *Content
+	world   *World
+	// Index of the next command to apply
+	next int
+	// Taken every snapshotInterval ticks as playback gets there, in tick order
+	snapshots []snapshot
+}
+
+func NewPlayback(r *Replay, level *Level, content *Content) (*Playback, error) {
+	if level.ID != r.Level {
+		return nil, fmt.Errorf("replay is for level %s, not %s", r.Level, level.ID)
+	}
+	// Play on the seed that was recorded even if the level changed since
+	seeded := *level
+	seeded.Seed = r.Seed
+	modified, modifiedContent, err := ApplyModifiers(&seeded, content, r.Modifiers)
+	if err != nil {
+		return nil, err
+	}
+	p := &Playback{
+		replay:  r,
+		level:   level,
+		content: content,
+	}
+	if r.Endless {
+		p.world = NewEndlessWorld(modified, modifiedContent)
+	} else {
+		p.world = NewWorld(modified, modifiedContent)
+	}
+	if r.Version < 2 {
+		p.world.hero = nil
+	}
+	p.snapshot()
+	return p, nil
+}
+
+// World being played back. Seeking replaces it.
+func (p *Playback) World() *World {
+	return p.world
+}
+
+func (p *Playback) Replay() *Replay {
+	return p.replay
+}
+
+func (p *Playback) Done() bool {
+	return p.world.tick >= p.replay.Ticks || p.world.Over()
+}
+
+func (p *Playback) snapshot() {
+	if n := len(p.snapshots); n > 0 && p.snapshots[n-1].tick >= p.world.tick {
+		return
+	}
+	var b bytes.Buffer
+	if err := p.world.WriteSave(&b); err != nil {
+		return
+	}
+	p.snapshots = append(p.snapshots, snapshot{tick: p.world.tick, next: p.next, save: b.Bytes()})
+}
+
+// Advance by one tick, applying the commands recorded for it. Commands
+// submitted to the world by anything else are dropped.
+func (p *Playback) Step() {
+	if p.Done() {
+		return
+	}
+	w := p.world
+	if w.tick%snapshotInterval == 0 {
+		p.snapshot()
+	}
+	w.commands = w.commands[:0]
+	for p.next < len(p.replay.Commands) && p.replay.Commands[p.next].Tick <= w.tick {
+		w.Submit(p.replay.Commands[p.next].Command)
+		p.next++
+	}
+	w.Step()
+}
+
+// Jump to a tick, clamped to the length of the replay
+func (p *Playback) Seek(tick int) error {
+	tick = max(0, min(tick, p.replay.Ticks))
+	if tick < p.world.tick || tick-p.world.tick > snapshotInterval {
+		// Snapshots are in order, so the last one at or before tick is the closest
+		i := len(p.snapshots) - 1
+		for i > 0 && p.snapshots[i].tick > tick {
+			i--
+		}
+		s := p.snapshots[i]
+		if s.tick > p.world.tick || tick < p.world.tick {
+			save, err := ReadSave(bytes.NewReader(s.save))
+			if err != nil {
+				return err
+			}
+			w, err := save.Restore(p.level, p.content)
+			if err != nil {
+				return err
+			}
+			p.world = w
+			p.next = s.next
+		}
+	}
+	for p.world.tick < tick && !p.Done() {
+		p.Step()
+	}
+	return nil
+}
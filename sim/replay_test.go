@@ -0,0 +1,102 @@
+package sim
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Replay of a game long enough for several snapshots, with commands given
+// along the way
+func recordTestReplay(t *testing.T) *Replay {
+	t.Helper()
+	w := newTestGame(t)
+	for tick := range 4*snapshotInterval + 37 {
+		switch tick {
+		case 2 * TicksPerSecond:
+			w.Submit(Command{Kind: CmdUseAbility, Ability: "cleave"})
+		case snapshotInterval + 5:
+			up := Vec2{Y: -1}
+			w.Submit(Command{Kind: CmdMoveHero, Move: &up})
+			w.Submit(Command{Kind: CmdCallNextWave})
+		case 2*snapshotInterval + 11:
+			w.Submit(Command{Kind: CmdUpgrade, TowerID: w.Towers()[0].ID})
+		}
+		w.Step()
+	}
+	r, err := w.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestPlaybackSeekMatchesPlayingThrough(t *testing.T) {
+	r := recordTestReplay(t)
+	level := loadTestLevel(t, "meadow")
+
+	// Saves at every tick of the replay played straight through
+	straight, err := NewPlayback(r, level, DefaultContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int][]byte{0: saveBytes(t, straight.World())}
+	for !straight.Done() {
+		straight.Step()
+		want[straight.World().Tick()] = saveBytes(t, straight.World())
+	}
+	if straight.World().Tick() != r.Ticks {
+		t.Fatalf("played through to tick %d, want %d", straight.World().Tick(), r.Ticks)
+	}
+
+	p, err := NewPlayback(r, level, DefaultContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeks := []struct {
+		name string
+		to   int
+		want int
+	}{
+		{"forward within a snapshot", 30, 30},
+		{"forward past several snapshots", 3*snapshotInterval + 7, 3*snapshotInterval + 7},
+		{"backward to before a command", snapshotInterval + 2, snapshotInterval + 2},
+		{"backward onto a snapshot", 2 * snapshotInterval, 2 * snapshotInterval},
+		{"forward one tick", 2*snapshotInterval + 1, 2*snapshotInterval + 1},
+		{"past the end", r.Ticks + 500, r.Ticks},
+		{"back to the start", -10, 0},
+		{"forward again after restarting", 2*snapshotInterval + 20, 2*snapshotInterval + 20},
+	}
+	for _, s := range seeks {
+		if err := p.Seek(s.to); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if got := p.World().Tick(); got != s.want {
+			t.Fatalf("%s: at tick %d, want %d", s.name, got, s.want)
+		}
+		if !bytes.Equal(saveBytes(t, p.World()), want[s.want]) {
+			t.Fatalf("%s: world at tick %d differs from playing straight through", s.name, s.want)
+		}
+	}
+}
+
+func TestPlaybackVersion1HasNoHero(t *testing.T) {
+	r := recordTestReplay(t)
+	r.Version = 1
+	p, err := NewPlayback(r, loadTestLevel(t, "meadow"), DefaultContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.World().Hero() != nil {
+		t.Fatal("a version 1 replay plays back with a hero")
+	}
+	// The recorded hero commands do nothing without one
+	for !p.Done() {
+		p.Step()
+	}
+	if err := p.Seek(snapshotInterval + 3); err != nil {
+		t.Fatal(err)
+	}
+	if p.World().Hero() != nil {
+		t.Error("seeking a version 1 replay brought the hero back")
+	}
+}
@@ -0,0 +1,29 @@
+package sim
+
+// Small deterministic random number generator (splitmix64). Its whole state
+// is one number, so it can be saved and restored with the world.
+type RNG struct {
+	State uint64
+}
+
+func NewRNG(seed uint64) RNG {
+	return RNG{State: seed}
+}
+
+func (r *RNG) Uint64() uint64 {
+	r.State += 0x9e3779b97f4a7c15
+	z := r.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Uniform in [0, 1)
+func (r *RNG) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Uniform in [0, n), n must be positive
+func (r *RNG) Intn(n int) int {
+	return int(r.Uint64() % uint64(n))
+}
@@ -0,0 +1,422 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Version of the save format written by WriteSave. Bump it whenever
+// worldState changes and add a migration from the previous version.
+const SaveVersion = 6
+
+// Upgrades the state of a save from the version it is keyed by to the next
+// one, working on the decoded JSON so old field layouts can be reshaped
+type saveMigration func(state map[string]any) error
+
+var saveMigrations = map[int]saveMigration{
+	// Version 2 keeps the command history for replays. Older saves never had
+	// it, so games resumed from them can't be replayed.
+	1: func(state map[string]any) error {
+		state["history"] = []any{}
+		state["historyComplete"] = false
+		return nil
+	},
+	// Version 3 adds endless mode, which older games never used
+	2: func(state map[string]any) error {
+		state["endless"] = false
+		return nil
+	},
+	// Version 4 adds modifiers and the seed they were played with. Older
+	// games were always played on the level's own seed, which a seed of 0
+	// stands for.
+	3: func(state map[string]any) error {
+		state["seed"] = 0
+		state["modifiers"] = []any{}
+		return nil
+	},
+	// Version 5 keeps the branches each enemy walks. Levels had a single
+	// path before, which is their only branch now.
+	4: func(state map[string]any) error {
+		enemies, _ := state["enemies"].([]any)
+		for _, e := range enemies {
+			if e, ok := e.(map[string]any); ok {
+				e["branches"] = []any{0}
+			}
+		}
+		return nil
+	},
+	// Version 6 adds the hero. Older games were played without one and carry
+	// on that way, so their command history still replays.
+	5: func(state map[string]any) error {
+		state["hero"] = nil
+		return nil
+	},
+}
+
+// On disk envelope of a saved game. The checksum covers the compacted state,
+// so reformatting the file doesn't break it but editing values does.
+type saveFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	State    json.RawMessage `json:"state"`
+}
+
+// Everything needed to continue a game exactly where it was left
+type worldState struct {
+	Level        string            `json:"level"`
+	Tick         int               `json:"tick"`
+	Gold         int               `json:"gold"`
+	Lives        int               `json:"lives"`
+	Leaked       int               `json:"leaked"`
+	NextWave     int               `json:"nextWave"`
+	NextWaveTick int               `json:"nextWaveTick"`
+	Active       []activeWaveState `json:"active"`
+	Enemies      []enemyState      `json:"enemies"`
+	Towers       []towerState      `json:"towers"`
+	Projectiles  []projectileState `json:"projectiles"`
+	NextID       int               `json:"nextId"`
+	Commands     []Command         `json:"commands,omitempty"`
+	RNG          uint64            `json:"rng"`
+	// Added in version 2
+	History         []TimedCommand `json:"history"`
+	HistoryComplete bool           `json:"historyComplete"`
+	// Added in version 3
+	Endless bool `json:"endless"`
+	// Added in version 4
+	Seed      uint64   `json:"seed"`
+	Modifiers []string `json:"modifiers"`
+	// Added in version 6, nil for games without a hero
+	Hero *heroState `json:"hero"`
+}
+
+type activeWaveState struct {
+	Index     int   `json:"index"`
+	StartTick int   `json:"startTick"`
+	Spawned   []int `json:"spawned"`
+}
+
+type enemyState struct {
+	ID       int      `json:"id"`
+	Type     string   `json:"type"`
+	HP       float64  `json:"hp"`
+	Distance float64  `json:"distance"`
+	Pos      Vec2     `json:"pos"`
+	Heading  Vec2     `json:"heading"`
+	Effects  []Effect `json:"effects,omitempty"`
+	// Added in version 5
+	Branches []int `json:"branches"`
+}
+
+type towerState struct {
+	ID          int       `json:"id"`
+	Type        string    `json:"type"`
+	Cell        Cell      `json:"cell"`
+	Level       int       `json:"level"`
+	Targeting   Targeting `json:"targeting"`
+	Kills       int       `json:"kills"`
+	DamageDealt float64   `json:"damageDealt"`
+	Invested    int       `json:"invested"`
+	Cooldown    int       `json:"cooldown"`
+}
+
+type heroState struct {
+	Pos            Vec2    `json:"pos"`
+	Move           Vec2    `json:"move"`
+	Heading        Vec2    `json:"heading"`
+	HP             float64 `json:"hp"`
+	Level          int     `json:"level"`
+	XP             int     `json:"xp"`
+	Kills          int     `json:"kills"`
+	Respawn        int     `json:"respawn"`
+	AttackCooldown int     `json:"attackCooldown"`
+	Cooldowns      []int   `json:"cooldowns"`
+}
+
+type projectileState struct {
+	ID       int     `json:"id"`
+	Pos      Vec2    `json:"pos"`
+	TargetID int     `json:"targetId"`
+	TowerID  int     `json:"towerId"`
+	Damage   float64 `json:"damage"`
+	Speed    float64 `json:"speed"`
+	Type     string  `json:"type"`
+	Crit     bool    `json:"crit,omitempty"`
+}
+
+// A saved game read back from disk, ready to be restored on its level
+type Save struct {
+	state worldState
+}
+
+func checksum(state []byte) (string, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, state); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(compact.Bytes())), nil
+}
+
+// Write the full state of the world in the current save format
+func (w *World) WriteSave(wr io.Writer) error {
+	s := worldState{
+		Level:        w.level.ID,
+		Tick:         w.tick,
+		Gold:         w.gold,
+		Lives:        w.lives,
+		Leaked:       w.leaked,
+		NextWave:     w.nextWave,
+		NextWaveTick: w.nextWaveTick,
+		NextID:       w.nextID,
+		Commands:     w.commands,
+		RNG:          w.rng.State,
+
+		History:         w.history,
+		HistoryComplete: w.historyComplete,
+		Endless:         w.endless,
+		Seed:            w.level.Seed,
+		Modifiers:       w.level.Modifiers,
+	}
+	for _, a := range w.active {
+		s.Active = append(s.Active, activeWaveState{Index: a.index, StartTick: a.startTick, Spawned: a.spawned})
+	}
+	for _, e := range w.enemies {
+		s.Enemies = append(s.Enemies, enemyState{
+			ID:       e.ID,
+			Type:     e.Type.ID,
+			HP:       e.HP,
+			Distance: e.Distance,
+			Pos:      e.Pos,
+			Heading:  e.Heading,
+			Effects:  e.Effects,
+			Branches: e.branches,
+		})
+	}
+	for _, t := range w.towers {
+		s.Towers = append(s.Towers, towerState{
+			ID:          t.ID,
+			Type:        t.Type.ID,
+			Cell:        t.Cell,
+			Level:       t.Level,
+			Targeting:   t.Targeting,
+			Kills:       t.Kills,
+			DamageDealt: t.DamageDealt,
+			Invested:    t.Invested,
+			Cooldown:    t.cooldown,
+		})
+	}
+	for _, p := range w.projectiles {
+		s.Projectiles = append(s.Projectiles, projectileState{
+			ID:       p.ID,
+			Pos:      p.Pos,
+			TargetID: p.TargetID,
+			TowerID:  p.TowerID,
+			Damage:   p.Damage,
+			Speed:    p.Speed,
+			Type:     p.Type.ID,
+			Crit:     p.Crit,
+		})
+	}
+
+	if h := w.hero; h != nil {
+		s.Hero = &heroState{
+			Pos:            h.Pos,
+			Move:           h.Move,
+			Heading:        h.Heading,
+			HP:             h.HP,
+			Level:          h.Level,
+			XP:             h.XP,
+			Kills:          h.Kills,
+			Respawn:        h.respawn,
+			AttackCooldown: h.attackCooldown,
+			Cooldowns:      h.cooldowns,
+		}
+	}
+
+	state, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	sum, err := checksum(state)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(saveFile{Version: SaveVersion, Checksum: sum, State: state}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = wr.Write(data)
+	return err
+}
+
+// Read a save, checking it for corruption and bringing older versions up to date
+func ReadSave(r io.Reader) (*Save, error) {
+	var f saveFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if len(f.State) == 0 {
+		return nil, errors.New("save has no state")
+	}
+	sum, err := checksum(f.State)
+	if err != nil {
+		return nil, err
+	}
+	if sum != f.Checksum {
+		return nil, fmt.Errorf("save is corrupted: checksum %s, want %s", sum, f.Checksum)
+	}
+	if f.Version > SaveVersion {
+		return nil, fmt.Errorf("save version %d is newer than this game supports (%d)", f.Version, SaveVersion)
+	}
+
+	state := []byte(f.State)
+	if f.Version < SaveVersion {
+		var m map[string]any
+		if err := json.Unmarshal(state, &m); err != nil {
+			return nil, err
+		}
+		for v := f.Version; v < SaveVersion; v++ {
+			migrate, ok := saveMigrations[v]
+			if !ok {
+				return nil, fmt.Errorf("no migration for save version %d", v)
+			}
+			if err := migrate(m); err != nil {
+				return nil, fmt.Errorf("migrating save version %d: %w", v, err)
+			}
+		}
+		if state, err = json.Marshal(m); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Save{}
+	if err := json.Unmarshal(state, &s.state); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Id of the level the save was made on
+func (s *Save) LevelID() string {
+	return s.state.Level
+}
+
+// Seed the game was played with
+func (s *Save) Seed() uint64 {
+	return s.state.Seed
+}
+
+// Rebuild the world the save was made from, on the level and content as
+// they were before its modifiers. Stepping it gives the same results the
+// original world would have.
+func (s *Save) Restore(level *Level, content *Content) (*World, error) {
+	st := s.state
+	if level.ID != st.Level {
+		return nil, fmt.Errorf("save is for level %s, not %s", st.Level, level.ID)
+	}
+	seeded := *level
+	if st.Seed != 0 {
+		seeded.Seed = st.Seed
+	}
+	level, content, err := ApplyModifiers(&seeded, content, st.Modifiers)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Endless && st.NextWave > len(level.Waves) {
+		return nil, fmt.Errorf("save is at wave %d of %d", st.NextWave, len(level.Waves))
+	}
+	w := NewWorld(level, content)
+	w.tick = st.Tick
+	w.gold = st.Gold
+	w.lives = st.Lives
+	w.leaked = st.Leaked
+	w.nextWave = st.NextWave
+	w.nextWaveTick = st.NextWaveTick
+	w.nextID = st.NextID
+	w.commands = st.Commands
+	w.rng = RNG{State: st.RNG}
+	w.history = st.History
+	w.historyComplete = st.HistoryComplete
+	w.endless = st.Endless
+
+	for _, a := range st.Active {
+		if a.Index < 0 || !w.hasWave(a.Index) || len(a.Spawned) != len(w.wave(a.Index).Groups) {
+			return nil, fmt.Errorf("save has an unknown wave %d", a.Index)
+		}
+		w.active = append(w.active, &activeWave{index: a.Index, startTick: a.StartTick, spawned: a.Spawned})
+	}
+	for _, e := range st.Enemies {
+		t, ok := content.Enemies[e.Type]
+		if !ok {
+			return nil, fmt.Errorf("save has an unknown enemy %q", e.Type)
+		}
+		route, err := level.routePath(e.Branches)
+		if err != nil {
+			return nil, fmt.Errorf("save has enemy %d on an unknown route: %w", e.ID, err)
+		}
+		enemy := &Enemy{
+			ID:       e.ID,
+			Type:     t,
+			HP:       e.HP,
+			Distance: e.Distance,
+			Pos:      e.Pos,
+			Heading:  e.Heading,
+			Effects:  e.Effects,
+			branches: e.Branches,
+		}
+		enemy.setRoute(route)
+		w.enemies = append(w.enemies, enemy)
+	}
+	for _, t := range st.Towers {
+		tt, ok := content.Towers[t.Type]
+		if !ok {
+			return nil, fmt.Errorf("save has an unknown tower %q", t.Type)
+		}
+		w.towers = append(w.towers, &Tower{
+			ID:          t.ID,
+			Type:        tt,
+			Cell:        t.Cell,
+			Level:       t.Level,
+			Targeting:   t.Targeting,
+			Kills:       t.Kills,
+			DamageDealt: t.DamageDealt,
+			Invested:    t.Invested,
+			cooldown:    t.Cooldown,
+		})
+	}
+	if h := st.Hero; h == nil {
+		w.hero = nil
+	} else if w.hero != nil {
+		w.hero.Pos = h.Pos
+		w.hero.Move = h.Move
+		w.hero.Heading = h.Heading
+		w.hero.HP = h.HP
+		w.hero.Level = h.Level
+		w.hero.XP = h.XP
+		w.hero.Kills = h.Kills
+		w.hero.respawn = h.Respawn
+		w.hero.attackCooldown = h.AttackCooldown
+		// Abilities added since the save start out ready
+		copy(w.hero.cooldowns, h.Cooldowns)
+	}
+	for _, p := range st.Projectiles {
+		tt, ok := content.Towers[p.Type]
+		if !ok {
+			return nil, fmt.Errorf("save has an unknown tower %q", p.Type)
+		}
+		w.projectiles = append(w.projectiles, &Projectile{
+			ID:       p.ID,
+			Pos:      p.Pos,
+			TargetID: p.TargetID,
+			TowerID:  p.TowerID,
+			Damage:   p.Damage,
+			Speed:    p.Speed,
+			Type:     tt,
+			Crit:     p.Crit,
+		})
+	}
+	return w, nil
+}
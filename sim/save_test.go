@@ -0,0 +1,161 @@
+package sim
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadTestLevel(t *testing.T, name string) *Level {
+	t.Helper()
+	f, err := os.Open(filepath.Join("..", "assets", "maps", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	l, err := LoadLevel(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// A game on meadow with towers along the road, the hero walking toward the
+// spawn and the first wave coming in
+func newTestGame(t *testing.T) *World {
+	t.Helper()
+	w := NewWorld(loadTestLevel(t, "meadow"), DefaultContent())
+	built := 0
+	for y := range w.level.Height {
+		for x := range w.level.Width {
+			c := Cell{X: x, Y: y}
+			if built < 3 && w.level.CanBuild(c) && w.level.DistanceToPath(c.Center()) < 2 {
+				w.Submit(Command{Kind: CmdBuild, Tower: w.content.TowerOrder[built%len(w.content.TowerOrder)], Cell: c})
+				built++
+			}
+		}
+	}
+	down := Vec2{Y: 1}
+	w.Submit(Command{Kind: CmdMoveHero, Move: &down})
+	w.Submit(Command{Kind: CmdCallNextWave})
+	return w
+}
+
+func stepTicks(w *World, n int) {
+	for range n {
+		w.Step()
+	}
+}
+
+func saveBytes(t *testing.T, w *World) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := w.WriteSave(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func restore(t *testing.T, data []byte, level *Level) *World {
+	t.Helper()
+	s, err := ReadSave(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := s.Restore(level, DefaultContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestSaveRestoreContinuesIdentically(t *testing.T) {
+	w := newTestGame(t)
+	stepTicks(w, 8*TicksPerSecond)
+	if len(w.Towers()) == 0 || len(w.Enemies()) == 0 || w.Hero() == nil {
+		t.Fatalf("game has %d towers, %d enemies and hero %v, want all of them", len(w.Towers()), len(w.Enemies()), w.Hero())
+	}
+
+	data := saveBytes(t, w)
+	restored := restore(t, data, loadTestLevel(t, "meadow"))
+	if got := saveBytes(t, restored); !bytes.Equal(got, data) {
+		t.Fatal("restored world saves differently")
+	}
+
+	for _, world := range []*World{w, restored} {
+		world.Submit(Command{Kind: CmdUseAbility, Ability: "frostNova"})
+		world.Submit(Command{Kind: CmdCallNextWave})
+	}
+	for tick := range 20 * TicksPerSecond {
+		w.Step()
+		restored.Step()
+		if tick%TicksPerSecond == 0 && !bytes.Equal(saveBytes(t, w), saveBytes(t, restored)) {
+			t.Fatalf("worlds differ %d ticks after restoring", tick+1)
+		}
+	}
+	if !bytes.Equal(saveBytes(t, w), saveBytes(t, restored)) {
+		t.Fatal("worlds differ at the end")
+	}
+}
+
+func TestSaveMigratesFromVersion1(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "save_v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := ReadSave(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LevelID() != "meadow" || s.Seed() != 0 {
+		t.Errorf("level %q and seed %d, want meadow and 0", s.LevelID(), s.Seed())
+	}
+	w, err := s.Restore(loadTestLevel(t, "meadow"), DefaultContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Tick() != 360 || len(w.Towers()) != 1 || len(w.Enemies()) != 3 {
+		t.Errorf("tick %d with %d towers and %d enemies, want 360, 1 and 3", w.Tick(), len(w.Towers()), len(w.Enemies()))
+	}
+	if w.Hero() != nil {
+		t.Error("a game saved before the hero has one")
+	}
+	if _, err := w.Replay(); err == nil {
+		t.Error("a game saved without history can be replayed")
+	}
+	// Enemies walk the level's only branch
+	for _, e := range w.Enemies() {
+		if len(e.route) < 2 {
+			t.Errorf("enemy %d has no route", e.ID)
+		}
+	}
+	stepTicks(w, 10*TicksPerSecond)
+	restored := restore(t, saveBytes(t, w), loadTestLevel(t, "meadow"))
+	if restored.Hero() != nil || restored.Tick() != w.Tick() {
+		t.Error("migrated game doesn't save and restore as it is")
+	}
+}
+
+func TestSaveRejectsCorruption(t *testing.T) {
+	w := newTestGame(t)
+	stepTicks(w, TicksPerSecond)
+	data := saveBytes(t, w)
+	if _, err := ReadSave(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change a digit, so the file is still valid JSON and only the checksum can tell
+	i := bytes.Index(data, []byte(`"gold": `)) + len(`"gold": `)
+	if i < len(`"gold": `) {
+		t.Fatal("save has no gold")
+	}
+	corrupted := bytes.Clone(data)
+	corrupted[i] ^= 1
+	_, err := ReadSave(bytes.NewReader(corrupted))
+	if err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Errorf("reading a save with a changed byte gave %v, want a corruption error", err)
+	}
+}
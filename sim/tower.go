@@ -0,0 +1,225 @@
+package sim
+
+import (
+	"math"
+)
+
+// Enum of how damage interacts with armor
+type DamageType string
+
+const (
+	// Reduced by armor
+	DamagePhysical DamageType = "physical"
+	// Ignores armor
+	DamageMagic DamageType = "magic"
+	// Ignores armor and slows the target down
+	DamageFrost DamageType = "frost"
+)
+
+// Enum of the rules a tower uses to pick its target
+type Targeting string
+
+const (
+	// Closest to the exit
+	TargetFirst Targeting = "first"
+	// Furthest from the exit
+	TargetLast      Targeting = "last"
+	TargetStrongest Targeting = "strongest"
+	TargetClosest   Targeting = "closest"
+)
+
+var TargetingModes = []Targeting{TargetFirst, TargetLast, TargetStrongest, TargetClosest}
+
+// The targeting mode after t, wrapping around
+func (t Targeting) Next() Targeting {
+	for i, m := range TargetingModes {
+		if m == t {
+			return TargetingModes[(i+1)%len(TargetingModes)]
+		}
+	}
+	return TargetFirst
+}
+
+type TowerType struct {
+	ID   string
+	Name string
+	Cost int
+	// Reach in tiles
+	Range  float64
+	Damage float64
+	// Shots / second
+	FireRate   float64
+	DamageType DamageType
+	// Tiles / second
+	ProjectileSpeed float64
+	// Radius in tiles around the impact that also takes damage, 0 for single target
+	Splash  float64
+	HitsAir bool
+	// Fraction of speed frost hits take away, and for how many seconds
+	Slow         float64
+	SlowDuration float64
+	// Chance of a shot dealing CritMultiplier times the damage
+	CritChance     float64
+	CritMultiplier float64
+}
+
+const MaxTowerLevel = 3
+
+// Fraction of the gold spent on a tower returned when selling it
+const sellRefund = 0.7
+
+// A square on the map, in tiles
+type Cell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func (c Cell) Center() Vec2 {
+	return Vec2{float64(c.X) + 0.5, float64(c.Y) + 0.5}
+}
+
+type Tower struct {
+	ID        int
+	Type      *TowerType
+	Cell      Cell
+	Level     int
+	Targeting Targeting
+	Kills     int
+	// Total damage dealt after armor
+	DamageDealt float64
+	// Gold spent on building and upgrading
+	Invested int
+	// Ticks until the tower can fire again
+	cooldown int
+}
+
+func (t *Tower) Damage() float64 {
+	return t.Type.Damage * math.Pow(1.5, float64(t.Level-1))
+}
+
+func (t *Tower) Range() float64 {
+	return t.Type.Range + 0.25*float64(t.Level-1)
+}
+
+// Damage per second against an unarmored target
+func (t *Tower) DPS() float64 {
+	return t.Damage() * t.Type.FireRate
+}
+
+// Gold needed for the next level, false at max level
+func (t *Tower) UpgradeCost() (int, bool) {
+	if t.Level >= MaxTowerLevel {
+		return 0, false
+	}
+	return t.Type.Cost * (t.Level + 1) / 2, true
+}
+
+// Gold returned when selling the tower
+func (t *Tower) SellValue() int {
+	return int(float64(t.Invested) * sellRefund)
+}
+
+func (w *World) build(typeID string, c Cell) {
+	t, ok := w.content.Towers[typeID]
+	if !ok || !w.level.CanBuild(c) || w.TowerAt(c) != nil || w.gold < t.Cost {
+		return
+	}
+	w.gold -= t.Cost
+	w.nextID++
+	w.emit(Event{Kind: EventBuild, ID: w.nextID, Type: t.ID, Pos: c.Center(), Amount: 1})
+	w.towers = append(w.towers, &Tower{
+		ID:        w.nextID,
+		Type:      t,
+		Cell:      c,
+		Level:     1,
+		Targeting: TargetFirst,
+		Invested:  t.Cost,
+	})
+}
+
+func (w *World) upgrade(id int) {
+	t := w.Tower(id)
+	if t == nil {
+		return
+	}
+	cost, ok := t.UpgradeCost()
+	if !ok || w.gold < cost {
+		return
+	}
+	w.gold -= cost
+	t.Invested += cost
+	t.Level++
+	w.emit(Event{Kind: EventBuild, ID: t.ID, Type: t.Type.ID, Pos: t.Cell.Center(), Amount: float64(t.Level)})
+}
+
+func (w *World) sell(id int) {
+	if w.level.NoSelling {
+		return
+	}
+	for i, t := range w.towers {
+		if t.ID == id {
+			w.gold += t.SellValue()
+			w.towers = append(w.towers[:i], w.towers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *World) setTargeting(id int, targeting Targeting) {
+	if t := w.Tower(id); t != nil {
+		t.Targeting = targeting
+	}
+}
+
+// Fire every tower that is ready and has an enemy in range
+func (w *World) updateTowers() {
+	for _, t := range w.towers {
+		if t.cooldown > 0 {
+			t.cooldown--
+			continue
+		}
+		target := w.pickTarget(t)
+		if target == nil {
+			continue
+		}
+		w.fire(t, target)
+		t.cooldown = secondsToTicks(1 / t.Type.FireRate)
+	}
+}
+
+func (w *World) pickTarget(t *Tower) *Enemy {
+	center := t.Cell.Center()
+	var best *Enemy
+	var bestScore float64
+	for _, e := range w.enemies {
+		if e.HP <= 0 || (e.Type.Flying && !t.Type.HitsAir) {
+			continue
+		}
+		d := e.Pos.Sub(center).Len()
+		if d > t.Range() {
+			continue
+		}
+		var score float64
+		switch t.Targeting {
+		case TargetFirst:
+			score = -w.remaining(e)
+		case TargetLast:
+			score = w.remaining(e)
+		case TargetStrongest:
+			score = e.HP
+		case TargetClosest:
+			score = -d
+		}
+		// Enemies are kept in spawn order, so ties go to the older one
+		if best == nil || score > bestScore {
+			best = e
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// Distance the enemy still has to walk to reach its exit
+func (w *World) remaining(e *Enemy) float64 {
+	return e.route.Length() - e.Distance
+}
@@ -0,0 +1,30 @@
+package sim
+
+import "math"
+
+// Position or direction in tile units
+type Vec2 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (v Vec2) Add(o Vec2) Vec2 {
+	return Vec2{v.X + o.X, v.Y + o.Y}
+}
+
+func (v Vec2) Sub(o Vec2) Vec2 {
+	return Vec2{v.X - o.X, v.Y - o.Y}
+}
+
+func (v Vec2) Mul(s float64) Vec2 {
+	return Vec2{v.X * s, v.Y * s}
+}
+
+func (v Vec2) Len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+// Linear interpolation towards o, t=0 gives v and t=1 gives o
+func (v Vec2) Lerp(o Vec2, t float64) Vec2 {
+	return v.Add(o.Sub(v).Mul(t))
+}
@@ -0,0 +1,57 @@
+package sim
+
+// Enemies of one type spawned at a regular interval
+type WaveGroup struct {
+	Enemy string
+	Count int
+	// Seconds after the wave starts before the first enemy spawns
+	Delay float64
+	// Seconds between two enemies of the group
+	Interval float64
+	// Index of the spawn the group comes in at, in the order of Level.Spawns
+	Spawn int
+}
+
+type Wave struct {
+	Groups []WaveGroup
+}
+
+// Number of enemies of each type in the wave, in order of first appearance
+type WaveEntry struct {
+	Enemy string
+	Count int
+}
+
+func (w Wave) Composition() []WaveEntry {
+	var entries []WaveEntry
+	index := map[string]int{}
+	for _, g := range w.Groups {
+		if i, ok := index[g.Enemy]; ok {
+			entries[i].Count += g.Count
+			continue
+		}
+		index[g.Enemy] = len(entries)
+		entries = append(entries, WaveEntry{Enemy: g.Enemy, Count: g.Count})
+	}
+	return entries
+}
+
+// A wave that has started and still has enemies left to spawn
+type activeWave struct {
+	index     int
+	startTick int
+	spawned   []int
+}
+
+func (a *activeWave) done(wave Wave) bool {
+	for i, g := range wave.Groups {
+		if a.spawned[i] < g.Count {
+			return false
+		}
+	}
+	return true
+}
+
+func secondsToTicks(s float64) int {
+	return int(s*TicksPerSecond + 0.5)
+}
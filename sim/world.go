@@ -0,0 +1,286 @@
+// Package sim is the deterministic tower defense simulation. It advances in
+// fixed ticks and knows nothing about rendering or input devices, so the same
+// game can run in the Ebiten window or headless.
+package sim
+
+import "slices"
+
+// Number of simulation ticks per second of game time
+const TicksPerSecond = 60
+
+// Game time of a single tick in seconds
+const TickDuration = 1.0 / TicksPerSecond
+
+type World struct {
+	level   *Level
+	content *Content
+	tick    int
+
+	gold   int
+	lives  int
+	leaked int
+
+	// Index of the next wave to start
+	nextWave int
+	// Tick the next wave starts on its own
+	nextWaveTick int
+	active       []*activeWave
+
+	enemies     []*Enemy
+	towers      []*Tower
+	projectiles []*Projectile
+	nextID      int
+	commands    []Command
+	events      []Event
+	rng         RNG
+
+	// Nil when the content has no hero or the game was played without one
+	hero *Hero
+	// Tiles the hero can walk on, row by row
+	walkable []bool
+	heroHome Vec2
+
+	// Keep generating waves after the level's own run out, the game only ends when lost
+	endless bool
+	// Waves generated for endless mode so far, after the level's own
+	generated []Wave
+
+	// Every command applied so far, enough to replay the game from its seed
+	history []TimedCommand
+	// False for worlds restored from saves that didn't keep their history
+	historyComplete bool
+}
+
+func NewWorld(level *Level, content *Content) *World {
+	w := &World{
+		level:        level,
+		content:      content,
+		gold:         level.StartingGold,
+		lives:        level.StartingLives,
+		nextWaveTick: secondsToTicks(level.FirstWaveDelay),
+		rng:          NewRNG(level.Seed),
+
+		historyComplete: true,
+	}
+	if content.Hero != nil {
+		w.walkable = walkableTiles(level)
+		w.heroHome = w.findHeroHome()
+		w.hero = newHero(content.Hero, w.heroHome)
+	}
+	return w
+}
+
+// A world that carries on with generated waves once the level's own run out
+func NewEndlessWorld(level *Level, content *Content) *World {
+	w := NewWorld(level, content)
+	w.endless = true
+	return w
+}
+
+// Advances the simulation by one fixed tick
+func (w *World) Step() {
+	w.events = w.events[:0]
+	if w.Over() {
+		return
+	}
+	w.applyCommands()
+	if w.hasWave(w.nextWave) && w.tick >= w.nextWaveTick {
+		w.startWave()
+	}
+	w.spawnEnemies()
+	w.moveEnemies()
+	w.updateHero()
+	w.updateTowers()
+	w.moveProjectiles()
+	w.tick++
+}
+
+func (w *World) startWave() {
+	wave := w.wave(w.nextWave)
+	w.active = append(w.active, &activeWave{
+		index:     w.nextWave,
+		startTick: w.tick,
+		spawned:   make([]int, len(wave.Groups)),
+	})
+	w.nextWave++
+	w.nextWaveTick = w.tick + secondsToTicks(w.level.WaveInterval)
+}
+
+// Start the next wave now, paying a bonus for the countdown skipped.
+// Earlier waves keep going, so waves can overlap.
+func (w *World) callNextWave() {
+	if !w.hasWave(w.nextWave) {
+		return
+	}
+	w.gold += w.EarlyCallBonus()
+	w.startWave()
+}
+
+func (w *World) spawnEnemies() {
+	remaining := w.active[:0]
+	for _, a := range w.active {
+		wave := w.wave(a.index)
+		for i, g := range wave.Groups {
+			for a.spawned[i] < g.Count {
+				due := a.startTick + secondsToTicks(g.Delay+g.Interval*float64(a.spawned[i]))
+				if w.tick < due {
+					break
+				}
+				w.spawnEnemy(g.Enemy, g.Spawn)
+				a.spawned[i]++
+			}
+		}
+		if !a.done(wave) {
+			remaining = append(remaining, a)
+		}
+	}
+	w.active = remaining
+}
+
+// Number of ticks simulated so far
+func (w *World) Tick() int {
+	return w.tick
+}
+
+// Game time simulated so far, in seconds
+func (w *World) Time() float64 {
+	return float64(w.tick) * TickDuration
+}
+
+func (w *World) Level() *Level {
+	return w.level
+}
+
+func (w *World) Content() *Content {
+	return w.content
+}
+
+func (w *World) Gold() int {
+	return w.gold
+}
+
+func (w *World) Lives() int {
+	return w.lives
+}
+
+// Number of enemies that reached the exit
+func (w *World) Leaked() int {
+	return w.leaked
+}
+
+// Enemies on the map, the slice must not be modified
+func (w *World) Enemies() []*Enemy {
+	return w.enemies
+}
+
+// Towers on the map, the slice must not be modified
+func (w *World) Towers() []*Tower {
+	return w.towers
+}
+
+// Tower with the given id, nil if it was sold
+func (w *World) Tower(id int) *Tower {
+	for _, t := range w.towers {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Tower built on the cell, nil if it is empty
+func (w *World) TowerAt(c Cell) *Tower {
+	for _, t := range w.towers {
+		if t.Cell == c {
+			return t
+		}
+	}
+	return nil
+}
+
+// Projectiles in flight, the slice must not be modified
+func (w *World) Projectiles() []*Projectile {
+	return w.projectiles
+}
+
+// Number of waves started so far
+func (w *World) WavesStarted() int {
+	return w.nextWave
+}
+
+// Number of waves designed for the level, endless mode goes on after them
+func (w *World) WaveCount() int {
+	return len(w.level.Waves)
+}
+
+func (w *World) Endless() bool {
+	return w.endless
+}
+
+// Id of the difficulty among the level's modifiers, the default one if there is none
+func (w *World) Difficulty() string {
+	for _, id := range w.level.Modifiers {
+		if slices.Contains(w.content.Difficulties, id) {
+			return id
+		}
+	}
+	return DefaultDifficulty
+}
+
+// The wave that starts next, false once all waves have started
+func (w *World) NextWave() (Wave, bool) {
+	if !w.hasWave(w.nextWave) {
+		return Wave{}, false
+	}
+	return w.wave(w.nextWave), true
+}
+
+// Seconds until the next wave starts on its own, false once all waves have started
+func (w *World) NextWaveIn() (float64, bool) {
+	if !w.hasWave(w.nextWave) {
+		return 0, false
+	}
+	return float64(max(0, w.nextWaveTick-w.tick)) * TickDuration, true
+}
+
+// Gold awarded for calling the next wave right now
+func (w *World) EarlyCallBonus() int {
+	in, ok := w.NextWaveIn()
+	if !ok {
+		return 0
+	}
+	return int(in * w.level.EarlyCallBonus)
+}
+
+func (w *World) Lost() bool {
+	return w.lives <= 0
+}
+
+func (w *World) Won() bool {
+	return !w.Lost() && !w.hasWave(w.nextWave) && len(w.active) == 0 && len(w.enemies) == 0
+}
+
+func (w *World) Over() bool {
+	return w.Won() || w.Lost()
+}
+
+// Points for the result of a game, lives count most and leftover gold breaks ties
+func (w *World) Score() int {
+	return w.lives*100 + w.gold
+}
+
+// Rating from 1 to 3 by the share of lives kept, 0 unless the level was won
+func (w *World) Stars() int {
+	if !w.Won() {
+		return 0
+	}
+	kept := float64(w.lives) / float64(w.level.StartingLives)
+	switch {
+	case kept >= 0.9:
+		return 3
+	case kept >= 0.5:
+		return 2
+	default:
+		return 1
+	}
+}
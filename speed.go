@@ -0,0 +1,112 @@
+package main
+
+// Enum of simulation speeds, the value is the number of ticks run per frame.
+// Scaling ticks rather than the tick length keeps the simulation deterministic.
+type Speed int
+
+const (
+	SpeedPaused Speed = 0
+	Speed1x     Speed = 1
+	Speed2x     Speed = 2
+	Speed3x     Speed = 3
+)
+
+func (s Speed) Label() string {
+	switch s {
+	case SpeedPaused:
+		return "||"
+	case Speed1x:
+		return "1x"
+	case Speed2x:
+		return "2x"
+	case Speed3x:
+		return "3x"
+	}
+	return ""
+}
+
+type SpeedControl struct {
+	speed Speed
+	// Speed to go back to when unpausing
+	resume Speed
+	// Single ticks queued by frame stepping while paused
+	steps int
+	// The main menu paused the game, so closing it should unpause
+	pausedByMenu bool
+}
+
+func NewSpeedControl() SpeedControl {
+	return SpeedControl{
+		speed:  Speed1x,
+		resume: Speed1x,
+	}
+}
+
+func (s *SpeedControl) Speed() Speed {
+	return s.speed
+}
+
+func (s *SpeedControl) Paused() bool {
+	return s.speed == SpeedPaused
+}
+
+func (s *SpeedControl) Set(speed Speed) {
+	if speed != SpeedPaused {
+		s.resume = speed
+	}
+	s.speed = speed
+	s.pausedByMenu = false
+}
+
+func (s *SpeedControl) TogglePause() {
+	if s.Paused() {
+		s.Set(s.resume)
+	} else {
+		s.Set(SpeedPaused)
+	}
+}
+
+// Cycle through 1x, 2x and 3x, unpausing if needed
+func (s *SpeedControl) SpeedUp() {
+	switch s.speed {
+	case Speed1x:
+		s.Set(Speed2x)
+	case Speed2x:
+		s.Set(Speed3x)
+	default:
+		s.Set(Speed1x)
+	}
+}
+
+// Run a single tick on the next frame, pausing first if needed
+func (s *SpeedControl) Step() {
+	if !s.Paused() {
+		s.Set(SpeedPaused)
+	}
+	s.steps++
+}
+
+// Pause while a menu is open
+func (s *SpeedControl) PauseForMenu() {
+	if !s.Paused() {
+		s.Set(SpeedPaused)
+		s.pausedByMenu = true
+	}
+}
+
+// Undo PauseForMenu, unless the player changed the speed in the meantime
+func (s *SpeedControl) ResumeFromMenu() {
+	if s.pausedByMenu {
+		s.Set(s.resume)
+	}
+}
+
+// Number of simulation ticks to run this frame
+func (s *SpeedControl) TicksThisFrame() int {
+	if s.Paused() {
+		n := s.steps
+		s.steps = 0
+		return n
+	}
+	return int(s.speed)
+}
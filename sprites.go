@@ -0,0 +1,186 @@
+package main
+
+import (
+	"image"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"icosahedron.com/tower-defense/anim"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// An enemy playing its death animation after it left the simulation
+type corpse struct {
+	pos    sim.Vec2
+	player *anim.Player
+}
+
+// Animation state of everything on the map, keyed by simulation ids
+type Sprites struct {
+	lib     anim.Library
+	atlas   *Atlas
+	enemies map[int]*anim.Player
+	towers  map[int]*anim.Player
+	// Nil while the hero is dead or the game has none
+	hero    *anim.Player
+	corpses []corpse
+}
+
+func NewSprites() *Sprites {
+	s := &Sprites{
+		atlas:   getAtlas(),
+		enemies: map[int]*anim.Player{},
+		towers:  map[int]*anim.Player{},
+	}
+	f, err := embeddedAssets.Open("assets/animations.json")
+	if err != nil {
+		log.Println("Failed to open animations:", err)
+		return s
+	}
+	defer f.Close()
+	s.lib, err = anim.Load(f)
+	if err != nil {
+		log.Println("Failed to load animations:", err)
+		return s
+	}
+	for id, sheet := range s.lib {
+		if _, ok := s.atlas.Sprite(sheet.Image); !ok {
+			log.Println("Missing sprite sheet", sheet.Image)
+			delete(s.lib, id)
+		}
+	}
+	return s
+}
+
+func towerSheetID(typeID string) string {
+	return "tower-" + typeID
+}
+
+// React to the events of the last simulation tick
+func (s *Sprites) AddEvents(events []sim.Event, now float64) {
+	for _, e := range events {
+		switch e.Kind {
+		case sim.EventFire:
+			if p := s.towers[e.ID]; p != nil {
+				p.Restart(anim.Attack, now)
+			}
+		case sim.EventDeath:
+			p := s.enemies[e.ID]
+			if p == nil {
+				continue
+			}
+			p.Restart(anim.Die, now)
+			if p.State() == anim.Die {
+				s.corpses = append(s.corpses, corpse{pos: e.Pos, player: p})
+			}
+			delete(s.enemies, e.ID)
+		case sim.EventHeroDeath:
+			if s.hero == nil {
+				continue
+			}
+			s.hero.Restart(anim.Die, now)
+			if s.hero.State() == anim.Die {
+				s.corpses = append(s.corpses, corpse{pos: e.Pos, player: s.hero})
+			}
+			s.hero = nil
+		}
+	}
+}
+
+// Start animations for new entities, drop the ones of removed entities and move states along
+func (s *Sprites) Update(w *sim.World) {
+	now := w.Time()
+	seen := map[int]bool{}
+	for _, e := range w.Enemies() {
+		seen[e.ID] = true
+		p := s.enemies[e.ID]
+		if p == nil {
+			sheet := s.lib[e.Type.ID]
+			if sheet == nil {
+				continue
+			}
+			p = anim.NewPlayer(sheet, now)
+			s.enemies[e.ID] = p
+		}
+		p.Face(e.Heading.X, e.Heading.Y)
+		if e.Heading != (sim.Vec2{}) {
+			p.Set(anim.Walk, now)
+		}
+	}
+	for id := range s.enemies {
+		if !seen[id] {
+			delete(s.enemies, id)
+		}
+	}
+
+	clear(seen)
+	for _, t := range w.Towers() {
+		seen[t.ID] = true
+		p := s.towers[t.ID]
+		if p == nil {
+			sheet := s.lib[towerSheetID(t.Type.ID)]
+			if sheet == nil {
+				continue
+			}
+			p = anim.NewPlayer(sheet, now)
+			s.towers[t.ID] = p
+		}
+		if p.State() == anim.Attack && p.Done(now) {
+			p.Set(anim.Idle, now)
+		}
+	}
+	for id := range s.towers {
+		if !seen[id] {
+			delete(s.towers, id)
+		}
+	}
+
+	if h := w.Hero(); h != nil && h.Alive() {
+		if s.hero == nil && s.lib[h.Type.ID] != nil {
+			s.hero = anim.NewPlayer(s.lib[h.Type.ID], now)
+		}
+		if s.hero != nil {
+			s.hero.Face(h.Heading.X, h.Heading.Y)
+			if h.Move != (sim.Vec2{}) {
+				s.hero.Set(anim.Walk, now)
+			} else {
+				s.hero.Set(anim.Idle, now)
+			}
+		}
+	} else {
+		s.hero = nil
+	}
+
+	alive := s.corpses[:0]
+	for _, c := range s.corpses {
+		if !c.player.Done(now) {
+			alive = append(alive, c)
+		}
+	}
+	s.corpses = alive
+}
+
+// Draw the current frame of p with its origin on pos, in tiles. False when p is nil.
+func (s *Sprites) Draw(screen *ebiten.Image, camera *Camera, p *anim.Player, pos sim.Vec2, now float64) bool {
+	if p == nil {
+		return false
+	}
+	sheet := p.Sheet()
+	f := p.Frame(now)
+	// Sub images keep the coordinates of the atlas, so offset the frame by where the sheet sits
+	img, _ := s.atlas.Sprite(sheet.Image)
+	offset := img.Bounds().Min
+	frame := img.SubImage(image.Rect(f.X, f.Y, f.X+f.W, f.Y+f.H).Add(offset)).(*ebiten.Image)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(pos.X*tileSize-float64(sheet.OriginX), pos.Y*tileSize-float64(sheet.OriginY))
+	op.GeoM.Concat(camera.GeoM())
+	screen.DrawImage(frame, op)
+	return true
+}
+
+func (s *Sprites) DrawCorpses(screen *ebiten.Image, camera *Camera, now float64) {
+	for _, c := range s.corpses {
+		s.Draw(screen, camera, c.player, c.pos, now)
+	}
+}
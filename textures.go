@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"icosahedron.com/tower-defense/atlas"
+)
+
+// Texture atlas made by cmd/atlaspack, with sprites looked up by name
+type Atlas struct {
+	image   *ebiten.Image
+	sprites map[string]*ebiten.Image
+}
+
+// Load assets/atlas/<name>.json and the image it points to
+func loadAtlas(name string) (*Atlas, error) {
+	f, err := embeddedAssets.Open("assets/atlas/" + name + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := atlas.Load(f)
+	if err != nil {
+		return nil, err
+	}
+	img, err := newImageFromFile(path.Join("assets/atlas", idx.Image))
+	if err != nil {
+		return nil, err
+	}
+	a := &Atlas{image: img, sprites: map[string]*ebiten.Image{}}
+	for name, r := range idx.Sprites {
+		a.sprites[name] = img.SubImage(r.Image()).(*ebiten.Image)
+	}
+	return a, nil
+}
+
+// The sprite with the given name, false if the atlas doesn't have it
+func (a *Atlas) Sprite(name string) (*ebiten.Image, bool) {
+	s, ok := a.sprites[name]
+	return s, ok
+}
+
+var spriteAtlas *Atlas
+
+// The atlas with every tile and sprite of the game, loaded on first use
+func getAtlas() *Atlas {
+	if spriteAtlas == nil {
+		a, err := loadAtlas("sprites")
+		if err != nil {
+			log.Fatal("Failed to load the sprite atlas: ", err)
+		}
+		spriteAtlas = a
+	}
+	return spriteAtlas
+}
+
+// Tile images by the ids used in level layers, id 0 is left empty
+type Tileset struct {
+	// Atlas sprite name of each tile
+	names []string
+	tiles []*ebiten.Image
+}
+
+// On disk representation of a Tileset
+type tilesetFile struct {
+	TileSize int      `json:"tileSize"`
+	Tiles    []string `json:"tiles"`
+}
+
+// Load assets/tilesets/<name>.json, resolving its tiles in the atlas
+func loadTileset(name string, a *Atlas) (*Tileset, error) {
+	f, err := embeddedAssets.Open("assets/tilesets/" + name + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tf tilesetFile
+	if err := json.NewDecoder(f).Decode(&tf); err != nil {
+		return nil, err
+	}
+	if tf.TileSize != tileSize {
+		return nil, fmt.Errorf("tileset %s has %dpx tiles, want %dpx", name, tf.TileSize, tileSize)
+	}
+	ts := &Tileset{names: tf.Tiles, tiles: make([]*ebiten.Image, len(tf.Tiles))}
+	for id, sprite := range tf.Tiles {
+		if sprite == "" {
+			continue
+		}
+		img, ok := a.Sprite(sprite)
+		if !ok {
+			return nil, fmt.Errorf("tileset %s: tile %d uses missing sprite %q", name, id, sprite)
+		}
+		ts.tiles[id] = img
+	}
+	return ts, nil
+}
+
+// Image of a tile, nil for empty and unknown ids
+func (ts *Tileset) Tile(id int) *ebiten.Image {
+	if id < 0 || id >= len(ts.tiles) {
+		return nil
+	}
+	return ts.tiles[id]
+}
+
+// Number of tile ids, including the empty id 0
+func (ts *Tileset) Len() int {
+	return len(ts.tiles)
+}
+
+// Atlas sprite name of a tile, empty for empty and unknown ids
+func (ts *Tileset) Name(id int) string {
+	if id < 0 || id >= len(ts.names) {
+		return ""
+	}
+	return ts.names[id]
+}
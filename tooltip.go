@@ -0,0 +1,61 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+const tooltipPadding = 8
+
+// Small text panel drawn next to a point on the screen
+type Tooltip struct {
+	face       font.Face
+	background color.Color
+	text       string
+	at         image.Point
+	visible    bool
+}
+
+func NewTooltip() Tooltip {
+	face, _ := loadFont(16)
+	return Tooltip{face: face, background: hexToColor(toolTipColor)}
+}
+
+func (t *Tooltip) Show(s string, at image.Point) {
+	t.text = s
+	t.at = at
+	t.visible = true
+}
+
+func (t *Tooltip) Hide() {
+	t.visible = false
+}
+
+func (t *Tooltip) Draw(screen *ebiten.Image) {
+	if !t.visible {
+		return
+	}
+	lineHeight := t.face.Metrics().Height.Ceil()
+	lines := strings.Split(t.text, "\n")
+	w := 0
+	for _, l := range lines {
+		w = max(w, font.MeasureString(t.face, l).Ceil())
+	}
+	r := image.Rect(0, 0, w+tooltipPadding*2, lineHeight*len(lines)+tooltipPadding*2)
+	// Show above the point so a finger doesn't cover it, but keep it on screen
+	r = r.Add(t.at.Sub(image.Point{r.Dx() / 2, r.Dy() + tooltipPadding*3}))
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	r = r.Add(image.Point{max(0, -r.Min.X) - max(0, r.Max.X-sw), max(0, -r.Min.Y) - max(0, r.Max.Y-sh)})
+
+	vector.DrawFilledRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), t.background, false)
+	for i, l := range lines {
+		y := r.Min.Y + tooltipPadding + t.face.Metrics().Ascent.Ceil() + i*lineHeight
+		text.Draw(screen, l, t.face, r.Min.X+tooltipPadding, y, hexToColor(textIdleColor))
+	}
+}
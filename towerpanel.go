@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Buttons along the left edge for picking the tower type to build
+func (g *Game) newBuildBar(res *uiResources, face font.Face) *widget.Container {
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+				HorizontalPosition: widget.AnchorLayoutPositionStart,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(6)),
+			widget.RowLayoutOpts.Spacing(6),
+		)),
+	)
+
+	g.buildButtons = map[string]*widget.Button{}
+	for _, id := range g.world.Content().TowerOrder {
+		t := g.world.Content().Towers[id]
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.TextAndImage("", face, &widget.ButtonImageImage{Idle: towerIcon(id)}, res.button.text),
+			widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.ToolTip(widget.NewToolTip(
+				widget.ToolTipOpts.Content(newTowerTypeToolTip(res, t)),
+			))),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				g.toggleBuildType(id)
+			}),
+		)
+		g.buildButtons[id] = b
+		c.AddChild(b)
+	}
+	return c
+}
+
+func newTowerTypeToolTip(res *uiResources, t *sim.TowerType) *widget.Container {
+	face, _ := loadFont(16)
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.toolTip.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(res.toolTip.padding),
+			widget.RowLayoutOpts.Spacing(2),
+		)),
+	)
+	lines := []string{
+		fmt.Sprintf("%s   %dg", t.Name, t.Cost),
+		fmt.Sprintf("DPS %.1f   Range %.1f   %s damage", t.Damage*t.FireRate, t.Range, damageTypeLabels[t.DamageType]),
+	}
+	if t.Splash > 0 {
+		lines = append(lines, fmt.Sprintf("Hits everything within %.1f tiles", t.Splash))
+	}
+	if t.Slow > 0 {
+		lines = append(lines, fmt.Sprintf("Slows by %.0f%% for %.0fs", t.Slow*100, t.SlowDuration))
+	}
+	if !t.HitsAir {
+		lines = append(lines, "Can't hit flying enemies")
+	}
+	for _, l := range lines {
+		c.AddChild(widget.NewText(widget.TextOpts.Text(l, face, res.text.idleColor)))
+	}
+	return c
+}
+
+func (g *Game) toggleBuildType(id string) {
+	if g.buildType == id {
+		g.buildType = ""
+		return
+	}
+	g.buildType = id
+	g.selectedTower = 0
+}
+
+func (g *Game) updateBuildBar() {
+	for i, id := range g.world.Content().TowerOrder {
+		if g.input.JustPressed(buildSlotAction(i)) {
+			g.toggleBuildType(id)
+		}
+	}
+	for id, b := range g.buildButtons {
+		t := g.world.Content().Towers[id]
+		label := fmt.Sprintf("%s %dg", t.Name, t.Cost)
+		if id == g.buildType {
+			label = "[" + label + "]"
+		}
+		b.Text().Label = label
+		b.GetWidget().Disabled = g.world.Gold() < t.Cost
+	}
+}
+
+// Stats and actions of the selected tower, shown along the right edge
+type TowerPanel struct {
+	container *widget.Container
+	title     *widget.Text
+	stats     *widget.Text
+	upgrade   *widget.Button
+	sell      *widget.Button
+	targeting *widget.Button
+}
+
+func (g *Game) newTowerPanel(res *uiResources, face font.Face) *TowerPanel {
+	smallFace, _ := loadFont(16)
+	p := &TowerPanel{}
+	p.container = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+				HorizontalPosition: widget.AnchorLayoutPositionEnd,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(8)),
+			widget.RowLayoutOpts.Spacing(6),
+		)),
+	)
+
+	p.title = widget.NewText(widget.TextOpts.Text("", face, res.text.idleColor))
+	p.container.AddChild(p.title)
+	p.stats = widget.NewText(widget.TextOpts.Text("", smallFace, res.text.idleColor))
+	p.container.AddChild(p.stats)
+
+	newButton := func(handler func(t *sim.Tower)) *widget.Button {
+		b := widget.NewButton(
+			widget.ButtonOpts.Image(res.button.image),
+			widget.ButtonOpts.TextPadding(widget.Insets{Left: 12, Right: 12, Top: 4, Bottom: 4}),
+			widget.ButtonOpts.Text("", smallFace, res.button.text),
+			widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+				Stretch: true,
+			})),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+				if t := g.world.Tower(g.selectedTower); t != nil {
+					handler(t)
+				}
+			}),
+		)
+		p.container.AddChild(b)
+		return b
+	}
+	p.upgrade = newButton(g.upgradeTower)
+	p.sell = newButton(g.sellTower)
+	p.targeting = newButton(func(t *sim.Tower) {
+		g.world.Submit(sim.Command{Kind: sim.CmdSetTargeting, TowerID: t.ID, Targeting: t.Targeting.Next()})
+	})
+	return p
+}
+
+func (p *TowerPanel) Update(g *Game) {
+	t := g.world.Tower(g.selectedTower)
+	if t == nil {
+		g.selectedTower = 0
+		p.container.GetWidget().Visibility = widget.Visibility_Hide
+		return
+	}
+	p.container.GetWidget().Visibility = widget.Visibility_Show
+
+	p.title.Label = fmt.Sprintf("%s (level %d)", t.Type.Name, t.Level)
+	p.stats.Label = fmt.Sprintf("DPS %.1f\nRange %.2f\n%s damage\nKills %d", t.DPS(), t.Range(), damageTypeLabels[t.Type.DamageType], t.Kills)
+	if cost, ok := t.UpgradeCost(); ok {
+		p.upgrade.Text().Label = fmt.Sprintf("Upgrade %dg", cost)
+		p.upgrade.GetWidget().Disabled = g.world.Gold() < cost
+	} else {
+		p.upgrade.Text().Label = "Max level"
+		p.upgrade.GetWidget().Disabled = true
+	}
+	p.sell.Text().Label = fmt.Sprintf("Sell +%dg", t.SellValue())
+	p.sell.GetWidget().Disabled = g.world.Level().NoSelling
+	p.targeting.Text().Label = "Target: " + targetingLabels[t.Targeting]
+}
+
+func (g *Game) upgradeTower(t *sim.Tower) {
+	g.world.Submit(sim.Command{Kind: sim.CmdUpgrade, TowerID: t.ID})
+}
+
+func (g *Game) sellTower(t *sim.Tower) {
+	if g.world.Level().NoSelling {
+		return
+	}
+	g.world.Submit(sim.Command{Kind: sim.CmdSell, TowerID: t.ID})
+	if g.selectedTower == t.ID {
+		g.selectedTower = 0
+	}
+}
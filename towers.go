@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/ebitenui/ebitenui/input"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"icosahedron.com/tower-defense/sim"
+)
+
+var towerColors = map[string]color.Color{
+	"arrow":  hexToColor("8a6a3f"),
+	"cannon": hexToColor("4b4f54"),
+	"mage":   hexToColor("6a4fb0"),
+	"frost":  hexToColor("6fc3df"),
+}
+
+func getTowerColor(id string) color.Color {
+	if c, ok := towerColors[id]; ok {
+		return c
+	}
+	return color.White
+}
+
+var damageTypeLabels = map[sim.DamageType]string{
+	sim.DamagePhysical: "Physical",
+	sim.DamageMagic:    "Magic",
+	sim.DamageFrost:    "Frost",
+}
+
+var targetingLabels = map[sim.Targeting]string{
+	sim.TargetFirst:     "First",
+	sim.TargetLast:      "Last",
+	sim.TargetStrongest: "Strongest",
+	sim.TargetClosest:   "Closest",
+}
+
+var (
+	rangeColor        = color.NRGBA{0xdf, 0xf4, 0xff, 0xc0}
+	rangeFillColor    = color.NRGBA{0xdf, 0xf4, 0xff, 0x20}
+	badRangeColor     = color.NRGBA{0xc0, 0x50, 0x3a, 0xc0}
+	badRangeFillColor = color.NRGBA{0xc0, 0x50, 0x3a, 0x30}
+)
+
+func (g *Game) drawTowers(screen *ebiten.Image) {
+	z := float32(g.camera.zoom)
+	for _, t := range g.world.Towers() {
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(t.Cell.X * tileSize), float32(t.Cell.Y * tileSize)})
+		if !g.sprites.Draw(screen, &g.camera, g.sprites.towers[t.ID], t.Cell.Center(), g.world.Time()) {
+			vector.DrawFilledRect(screen, float32(x)+2*z, float32(y)+2*z, (tileSize-4)*z, (tileSize-4)*z, getTowerColor(t.Type.ID), false)
+		}
+		// One pip per level along the bottom edge
+		for i := range t.Level {
+			vector.DrawFilledRect(screen, float32(x)+(3+4*float32(i))*z, float32(y)+(tileSize-5)*z, 2*z, 2*z, color.White, false)
+		}
+	}
+}
+
+func (g *Game) drawProjectiles(screen *ebiten.Image) {
+	for _, p := range g.world.Projectiles() {
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(p.Pos.X * tileSize), float32(p.Pos.Y * tileSize)})
+		vector.DrawFilledCircle(screen, float32(x), float32(y), 1.5*float32(g.camera.zoom), getTowerColor(p.Type.ID), true)
+	}
+}
+
+// Attack range of the hovered and selected towers, and of the tower about to be built
+func (g *Game) drawRanges(screen *ebiten.Image) {
+	shown := map[int]bool{}
+	for _, id := range []int{g.hoveredTower, g.selectedTower} {
+		if t := g.world.Tower(id); t != nil && !shown[id] {
+			shown[id] = true
+			g.drawRange(screen, t.Cell.Center(), t.Range(), true)
+		}
+	}
+
+	t, ok := g.world.Content().Towers[g.buildType]
+	if !ok {
+		return
+	}
+	tile, ok := g.targetTile()
+	if !ok {
+		return
+	}
+	g.drawRange(screen, cellOf(tile).Center(), t.Range, g.canBuild(t, tile))
+}
+
+func (g *Game) drawRange(screen *ebiten.Image, center sim.Vec2, r float64, valid bool) {
+	x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(center.X * tileSize), float32(center.Y * tileSize)})
+	radius := float32(r * tileSize * g.camera.zoom)
+	stroke, fill := rangeColor, rangeFillColor
+	if !valid {
+		stroke, fill = badRangeColor, badRangeFillColor
+	}
+	vector.DrawFilledCircle(screen, float32(x), float32(y), radius, fill, true)
+	vector.StrokeCircle(screen, float32(x), float32(y), radius, 2, stroke, true)
+}
+
+func (g *Game) canBuild(t *sim.TowerType, tile image.Point) bool {
+	c := cellOf(tile)
+	return g.world.Level().CanBuild(c) && g.world.TowerAt(c) == nil && g.world.Gold() >= t.Cost
+}
+
+func cellOf(tile image.Point) sim.Cell {
+	return sim.Cell{X: tile.X, Y: tile.Y}
+}
+
+// Enemy drawn under a point on the screen, with the hit area grown to a comfortable touch target
+func (g *Game) enemyAt(p image.Point) *sim.Enemy {
+	var best *sim.Enemy
+	bestDist := math.Inf(1)
+	for _, e := range g.world.Enemies() {
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(e.Pos.X * tileSize), float32(e.Pos.Y * tileSize)})
+		d := math.Hypot(x-float64(p.X), y-float64(p.Y))
+		r := max(float64(getEnemyStyle(e.Type.ID).radius)*g.camera.zoom, minTouchTarget/2)
+		if d <= r && d < bestDist {
+			best, bestDist = e, d
+		}
+	}
+	return best
+}
+
+func describeTower(t *sim.Tower) string {
+	lines := []string{
+		fmt.Sprintf("%s (level %d)", t.Type.Name, t.Level),
+		fmt.Sprintf("DPS %.1f   %s damage", t.DPS(), damageTypeLabels[t.Type.DamageType]),
+		fmt.Sprintf("Targeting %s   Kills %d", targetingLabels[t.Targeting], t.Kills),
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describeEnemy(e *sim.Enemy) string {
+	lines := []string{
+		e.Type.Name,
+		fmt.Sprintf("HP %.0f/%.0f   Armor %.0f", e.HP, e.Type.HP, e.Type.Armor),
+		fmt.Sprintf("Speed %.1f", e.Speed()),
+	}
+	for _, effect := range e.Effects {
+		switch effect.Kind {
+		case sim.EffectSlow:
+			lines = append(lines, fmt.Sprintf("Slowed %.0f%% for %.1fs", effect.Strength*100, float64(effect.Remaining)*sim.TickDuration))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Show what is under the mouse or gamepad cursor: an enemy, a tower or nothing
+func (g *Game) updateHover() {
+	g.hoveredTower = 0
+	var p image.Point
+	switch {
+	case g.cursor.visible:
+		x, y := g.camera.WorldToScreen(mgl32.Vec2{float32(g.cursor.Tile().X*tileSize + tileSize/2), float32(g.cursor.Tile().Y*tileSize + tileSize/2)})
+		p = image.Pt(int(x), int(y))
+	case input.UIHovered:
+		g.hoverTip.Hide()
+		return
+	default:
+		p = image.Pt(ebiten.CursorPosition())
+	}
+
+	if e := g.enemyAt(p); e != nil {
+		g.hoverTip.Show(describeEnemy(e), p)
+		return
+	}
+	if tile, ok := g.targetTile(); ok {
+		if t := g.world.TowerAt(cellOf(tile)); t != nil {
+			g.hoveredTower = t.ID
+			g.hoverTip.Show(describeTower(t), p)
+			return
+		}
+	}
+	g.hoverTip.Hide()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/font"
+	"icosahedron.com/tower-defense/sim"
+)
+
+// Panel at the bottom of the screen listing the enemies of the upcoming wave
+type WavePreview struct {
+	res       *uiResources
+	face      font.Face
+	smallFace font.Face
+
+	container *widget.Container
+	title     *widget.Text
+	entries   *widget.Container
+	// Index of the wave the entries were built for, -1 before the first build
+	shownWave int
+}
+
+func newWavePreview(res *uiResources) *WavePreview {
+	face, _ := loadFont(18)
+	smallFace, _ := loadFont(14)
+	p := &WavePreview{
+		res:       res,
+		face:      face,
+		smallFace: smallFace,
+		shownWave: -1,
+	}
+
+	p.container = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(res.background),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				VerticalPosition:   widget.AnchorLayoutPositionEnd,
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+			}),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(8)),
+			widget.RowLayoutOpts.Spacing(6),
+		)),
+	)
+
+	p.title = widget.NewText(
+		widget.TextOpts.Text("", face, res.text.idleColor),
+	)
+	p.container.AddChild(p.title)
+
+	p.entries = widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(14),
+		)),
+	)
+	p.container.AddChild(p.entries)
+
+	return p
+}
+
+func (p *WavePreview) Update(w *sim.World) {
+	wave, ok := w.NextWave()
+	if !ok {
+		p.container.GetWidget().Visibility = widget.Visibility_Hide
+		return
+	}
+	p.container.GetWidget().Visibility = widget.Visibility_Show
+
+	in, _ := w.NextWaveIn()
+	if w.Endless() {
+		p.title.Label = fmt.Sprintf("Wave %d in %ds", w.WavesStarted()+1, int(math.Ceil(in)))
+	} else {
+		p.title.Label = fmt.Sprintf("Wave %d of %d in %ds", w.WavesStarted()+1, w.WaveCount(), int(math.Ceil(in)))
+	}
+
+	if p.shownWave != w.WavesStarted() {
+		p.shownWave = w.WavesStarted()
+		p.entries.RemoveChildren()
+		for _, e := range wave.Composition() {
+			if t, ok := w.Content().Enemies[e.Enemy]; ok {
+				p.entries.AddChild(p.newEntry(t, e.Count))
+			}
+		}
+	}
+}
+
+// Icon, count and trait badges of one enemy type, with its stats in a tooltip
+func (p *WavePreview) newEntry(t *sim.EnemyType, count int) *widget.Container {
+	c := widget.NewContainer(
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.ToolTip(widget.NewToolTip(
+				widget.ToolTipOpts.Content(p.newEntryToolTip(t)),
+			)),
+		),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Spacing(4),
+		)),
+	)
+
+	c.AddChild(widget.NewGraphic(
+		widget.GraphicOpts.Image(enemyIcon(t.ID)),
+		widget.GraphicOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	))
+	c.AddChild(widget.NewText(
+		widget.TextOpts.Text(fmt.Sprintf("x%d", count), p.face, p.res.text.idleColor),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	))
+	for _, trait := range t.Traits() {
+		c.AddChild(widget.NewGraphic(
+			widget.GraphicOpts.Image(traitIcon(trait)),
+			widget.GraphicOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+				Position: widget.RowLayoutPositionCenter,
+			})),
+		))
+	}
+	return c
+}
+
+func (p *WavePreview) newEntryToolTip(t *sim.EnemyType) *widget.Container {
+	c := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(p.res.toolTip.background),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(p.res.toolTip.padding),
+			widget.RowLayoutOpts.Spacing(2),
+		)),
+	)
+	c.AddChild(widget.NewText(widget.TextOpts.Text(t.Name, p.face, p.res.text.idleColor)))
+	stats := fmt.Sprintf("HP %.0f   Speed %.1f   Bounty %dg", t.HP, t.Speed, t.Bounty)
+	if t.Armor > 0 {
+		stats += fmt.Sprintf("   Armor %.0f", t.Armor)
+	}
+	c.AddChild(widget.NewText(widget.TextOpts.Text(stats, p.smallFace, p.res.text.idleColor)))
+	c.AddChild(widget.NewText(widget.TextOpts.Text(fmt.Sprintf("Costs %d lives if it gets through", t.Damage), p.smallFace, p.res.text.disabledColor)))
+	for _, trait := range t.Traits() {
+		c.AddChild(widget.NewText(widget.TextOpts.Text(traitDescriptions[trait], p.smallFace, traitColors[trait])))
+	}
+	return c
+}